@@ -1,7 +1,14 @@
 package m2obj
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"sync"
 	"time"
 )
@@ -12,41 +19,190 @@ func (e noBoundObjErr) Error() string {
 	return "no bound object to be synced"
 }
 
+func (e noBoundObjErr) Is(target error) bool {
+	return target == ErrNoBoundObj
+}
+
+type syncerStartedErr struct{}
+
+func (e syncerStartedErr) Error() string {
+	return "the FileSyncer has been started"
+}
+
+func (e syncerStartedErr) Is(target error) bool {
+	return target == ErrSyncerStarted
+}
+
+type syncerClosedErr struct{}
+
+func (e syncerClosedErr) Error() string {
+	return "the FileSyncer has been closed"
+}
+
+func (e syncerClosedErr) Is(target error) bool {
+	return target == ErrSyncerClosed
+}
+
 // FileSyncer
 //
 // Data serialization management and synchronization between files and memory using Formatter
 //
 // !!! Only Bind to GROUP Object please !!!
 type FileSyncer struct {
-	// HardLoad
+	// hardLoad
 	//
 	// Uses SetVal(), instead of GroupMerge(). This means that each loading will clear all previous data
 	//
 	// DEFAULT: false
-	HardLoad bool
-	// AutoSaveTiming
+	hardLoad bool
+	// autoSaveTiming
 	//
 	//  <0: Don't auto save
 	//  =0: DEFAULT. Auto save when obj changed
 	//  >0(ms): Auto save when timer triggered
-	AutoSaveTiming int64
-	// AutoLoadTiming
+	autoSaveTiming int64
+	// autoLoadTiming
 	//
 	//  <=0: DEFAULT. Don't auto load
 	//  >0(ms): Auto load when timer triggered
-	// while AutoLoadTiming > 0, the AutoSaveTiming is disabled
-	AutoLoadTiming int64
-	// path to the file
+	// while autoLoadTiming > 0, the autoSaveTiming is disabled
+	autoLoadTiming int64
+	// saveDebounce
+	//
+	// While auto saving when obj changed, waits for the milliseconds after the last change to save once,
+	// but no longer than saveMaxWait milliseconds after the first unsaved change (<=0: no limit).
+	//
+	// DEFAULT: 0, saves at once
+	saveDebounce int64
+	saveMaxWait  int64
+	// the timer of the debounced saving, and the time of the first change waiting for it
+	saveTimer   *time.Timer
+	saveFirst   time.Time
+	saveTimerID int64
+	// validate
+	//
+	// Checks the content before it is loaded into obj, see SetValidate
+	validate func(candidate *Object) error
+	// onError
+	//
+	// Receives the errors of the automatic saving ("save") and loading ("load"), see SetOnError
+	onError func(op string, err error)
+	// path to the file, a leading `~` is expanded to the home directory
 	filePath string
+	// the permission bits of the saved file
+	//
+	// DEFAULT: 0644
+	fileMode os.FileMode
 	// an instance of a kind of data formatters, which must implements the interface Formatter
 	formatter Formatter
-	// bound object
+	// bound object, the content of it is protected by the lock of its own object tree
 	obj *Object
+	// unregisters the listener on the bound object, see Object.Watch
+	unwatch func()
+	// if there are changes of the bound object not saved yet
+	dirty bool
+	// the file last loaded, to find out if the file is changed since then
+	stamp fileStamp
+	// also reloads on the file change notifications of the system (inotify on Linux), see SetNotify
+	notify bool
+	// wakes the timer loop up when the timings are changed
+	wake chan struct{}
+	// stops the timer loop, closed by Close
+	stop chan struct{}
+	// closed when the timer loop exits, nil if the FileSyncer is not started
+	done   chan struct{}
+	closed bool
 
-	// file mutex
+	// file mutex, also protects the fields of the FileSyncer
 	fileMutex sync.Mutex
-	// object mutex
-	objMutex sync.Mutex
+}
+
+// FileSyncerOption
+//
+// Sets an option of a FileSyncer, see NewFileSyncer.
+type FileSyncerOption func(fs *FileSyncer)
+
+// WithHardLoad
+//
+// See FileSyncer.SetHardLoad.
+func WithHardLoad(hardLoad bool) FileSyncerOption {
+	return func(fs *FileSyncer) {
+		fs.hardLoad = hardLoad
+	}
+}
+
+// WithAutoSaveTiming
+//
+// See FileSyncer.SetAutoSaveTiming.
+func WithAutoSaveTiming(ms int64) FileSyncerOption {
+	return func(fs *FileSyncer) {
+		fs.autoSaveTiming = ms
+	}
+}
+
+// WithAutoLoadTiming
+//
+// See FileSyncer.SetAutoLoadTiming.
+func WithAutoLoadTiming(ms int64) FileSyncerOption {
+	return func(fs *FileSyncer) {
+		fs.autoLoadTiming = ms
+	}
+}
+
+// fileStamp
+//
+// The state of a file, the file is not changed if the modification time and the size are the same,
+// or the content hash is the same.
+type fileStamp struct {
+	valid   bool
+	modTime time.Time
+	size    int64
+	hash    [sha256.Size]byte
+}
+
+// WithSaveDebounce
+//
+// See FileSyncer.SetSaveDebounce.
+func WithSaveDebounce(ms, maxWaitMs int64) FileSyncerOption {
+	return func(fs *FileSyncer) {
+		fs.saveDebounce, fs.saveMaxWait = ms, maxWaitMs
+	}
+}
+
+// WithValidate
+//
+// See FileSyncer.SetValidate.
+func WithValidate(validate func(candidate *Object) error) FileSyncerOption {
+	return func(fs *FileSyncer) {
+		fs.validate = validate
+	}
+}
+
+// WithOnError
+//
+// See FileSyncer.SetOnError.
+func WithOnError(onError func(op string, err error)) FileSyncerOption {
+	return func(fs *FileSyncer) {
+		fs.onError = onError
+	}
+}
+
+// WithNotify
+//
+// See FileSyncer.SetNotify.
+func WithNotify(notify bool) FileSyncerOption {
+	return func(fs *FileSyncer) {
+		fs.notify = notify
+	}
+}
+
+// WithFileMode
+//
+// See FileSyncer.SetFileMode.
+func WithFileMode(mode os.FileMode) FileSyncerOption {
+	return func(fs *FileSyncer) {
+		fs.fileMode = mode
+	}
 }
 
 func (fs *FileSyncer) GetFilePath() (filePath string) {
@@ -59,71 +215,368 @@ func (fs *FileSyncer) SetFilePath(filePath string) {
 	fs.fileMutex.Lock()
 	defer fs.fileMutex.Unlock()
 	fs.filePath = filePath
+	fs.stamp = fileStamp{}
 	return
 }
 
 func (fs *FileSyncer) GetBoundObject() (obj *Object) {
-	fs.objMutex.Lock()
-	defer fs.objMutex.Unlock()
+	fs.fileMutex.Lock()
+	defer fs.fileMutex.Unlock()
 	return fs.obj
 }
 
 func (fs *FileSyncer) SetFormatter(formatter Formatter) {
 	fs.fileMutex.Lock()
 	defer fs.fileMutex.Unlock()
-	fs.objMutex.Lock()
-	defer fs.objMutex.Unlock()
 	fs.formatter = formatter
 	return
 }
 
+func (fs *FileSyncer) GetFileMode() (mode os.FileMode) {
+	fs.fileMutex.Lock()
+	defer fs.fileMutex.Unlock()
+	return fs.fileMode
+}
+
+// SetFileMode
+//
+// The permission bits of the saved file.
+//
+// DEFAULT: 0644
+func (fs *FileSyncer) SetFileMode(mode os.FileMode) {
+	fs.fileMutex.Lock()
+	defer fs.fileMutex.Unlock()
+	fs.fileMode = mode
+	return
+}
+
+func (fs *FileSyncer) GetSaveDebounce() (ms, maxWaitMs int64) {
+	fs.fileMutex.Lock()
+	defer fs.fileMutex.Unlock()
+	return fs.saveDebounce, fs.saveMaxWait
+}
+
+// SetSaveDebounce
+//
+// While auto saving when obj changed (AutoSaveTiming == 0), waits for ms milliseconds after the last change to save once,
+// but no longer than maxWaitMs milliseconds after the first unsaved change (<=0: no limit). So a burst of changes is saved only once.
+//
+// DEFAULT: 0, saves at once in every change
+func (fs *FileSyncer) SetSaveDebounce(ms, maxWaitMs int64) {
+	fs.fileMutex.Lock()
+	defer fs.fileMutex.Unlock()
+	fs.saveDebounce, fs.saveMaxWait = ms, maxWaitMs
+	return
+}
+
+// SetValidate
+//
+// Sets the func to check the content of the file before it is loaded. The candidate is what the bound object will be after the loading
+// (the content of the file for HardLoad, or the bound object merged with it). If it returns an error, the bound object stays untouched,
+// and the error is returned by Load (and reported to the OnError while auto loading).
+// The rejected file is not regarded as loaded, so the auto loading tries it again, e.g. after the validate is changed.
+//
+// The validate is called with the bound object locked, so it must not access the bound object.
+//
+// Example:
+//
+//   fs.SetValidate(schema.Check) // see the m2schema package
+func (fs *FileSyncer) SetValidate(validate func(candidate *Object) error) {
+	fs.fileMutex.Lock()
+	defer fs.fileMutex.Unlock()
+	fs.validate = validate
+	return
+}
+
+// SetOnError
+//
+// Sets the func to receive the errors of the automatic saving and loading, which are dropped by default.
+// The op is "save" or "load". It is called without any lock, but may be called from multiple goroutines at the same time.
+func (fs *FileSyncer) SetOnError(onError func(op string, err error)) {
+	fs.fileMutex.Lock()
+	defer fs.fileMutex.Unlock()
+	fs.onError = onError
+	return
+}
+
+// reportErr
+//
+// Reports the error of an automatic saving or loading to the onError.
+func (fs *FileSyncer) reportErr(op string, err error) {
+	if err == nil {
+		return
+	}
+	fs.fileMutex.Lock()
+	onError := fs.onError
+	fs.fileMutex.Unlock()
+	if onError != nil {
+		onError(op, err)
+	}
+}
+
+func (fs *FileSyncer) GetNotify() (notify bool) {
+	fs.fileMutex.Lock()
+	defer fs.fileMutex.Unlock()
+	return fs.notify
+}
+
+// SetNotify
+//
+// While auto loading, also reloads as soon as the system notifies that the file is changed (inotify on Linux),
+// instead of only waiting for the timer. The timer still works as the fallback, and it is the only way on the other systems.
+//
+// DEFAULT: false
+func (fs *FileSyncer) SetNotify(notify bool) {
+	fs.fileMutex.Lock()
+	defer fs.fileMutex.Unlock()
+	fs.notify = notify
+	fs.wakeUp()
+	return
+}
+
+func (fs *FileSyncer) GetHardLoad() (hardLoad bool) {
+	fs.fileMutex.Lock()
+	defer fs.fileMutex.Unlock()
+	return fs.hardLoad
+}
+
+// SetHardLoad
+//
+// Uses SetVal(), instead of GroupMerge() to load. This means that each loading will clear all previous data
+//
+// DEFAULT: false
+func (fs *FileSyncer) SetHardLoad(hardLoad bool) {
+	fs.fileMutex.Lock()
+	defer fs.fileMutex.Unlock()
+	if fs.hardLoad != hardLoad {
+		// the file loaded in another way makes a different result
+		fs.stamp = fileStamp{}
+	}
+	fs.hardLoad = hardLoad
+	return
+}
+
+func (fs *FileSyncer) GetAutoSaveTiming() (ms int64) {
+	fs.fileMutex.Lock()
+	defer fs.fileMutex.Unlock()
+	return fs.autoSaveTiming
+}
+
+// SetAutoSaveTiming
+//
+//  <0: Don't auto save
+//  =0: DEFAULT. Auto save when obj changed
+//  >0(ms): Auto save when timer triggered, after Start. Nothing is written if obj is not changed since the last saving
+func (fs *FileSyncer) SetAutoSaveTiming(ms int64) {
+	fs.fileMutex.Lock()
+	defer fs.fileMutex.Unlock()
+	fs.autoSaveTiming = ms
+	fs.wakeUp()
+	return
+}
+
+func (fs *FileSyncer) GetAutoLoadTiming() (ms int64) {
+	fs.fileMutex.Lock()
+	defer fs.fileMutex.Unlock()
+	return fs.autoLoadTiming
+}
+
+// SetAutoLoadTiming
+//
+//  <=0: DEFAULT. Don't auto load
+//  >0(ms): Auto load when timer triggered, after Start
+// while AutoLoadTiming > 0, the AutoSaveTiming is disabled
+func (fs *FileSyncer) SetAutoLoadTiming(ms int64) {
+	fs.fileMutex.Lock()
+	defer fs.fileMutex.Unlock()
+	fs.autoLoadTiming = ms
+	fs.wakeUp()
+	return
+}
+
+// wakeUp
+//
+// Lets the timer loop read the timings again, must be called with fileMutex locked.
+func (fs *FileSyncer) wakeUp() {
+	select {
+	case fs.wake <- struct{}{}:
+	default:
+	}
+}
+
 func (fs *FileSyncer) Save() (err error) {
-	fs.objMutex.Lock()
+	fs.fileMutex.Lock()
+	defer fs.fileMutex.Unlock()
+	return fs.save()
+}
+
+// saveIfDirty
+//
+// Likes Save, but does nothing if there are no changes of the bound object since the last saving.
+// Used by the automatic saving, so the file is not rewritten for nothing.
+func (fs *FileSyncer) saveIfDirty() (err error) {
+	fs.fileMutex.Lock()
+	defer fs.fileMutex.Unlock()
+	if !fs.dirty {
+		return nil
+	}
+	return fs.save()
+}
+
+// save
+//
+// Saves the bound object into the file, must be called with fileMutex locked.
+func (fs *FileSyncer) save() (err error) {
 	if fs.obj == nil {
-		fs.objMutex.Unlock()
 		return noBoundObjErr{}
-	} else {
-		fs.objMutex.Unlock()
 	}
-	var buf []byte
-	fs.objMutex.Lock()
+	// the changes after marshalling make it dirty again
+	fs.dirty = false
+	var (
+		buf  []byte
+		path string
+	)
 	buf, err = fs.formatter.Marshal(fs.obj)
-	fs.objMutex.Unlock()
 	if err == nil {
-		fs.fileMutex.Lock()
-		err = ioutil.WriteFile(fs.filePath, buf, 0644)
-		fs.fileMutex.Unlock()
+		path, err = expandPath(fs.filePath)
+	}
+	if err == nil {
+		err = writeFileAtomic(path, buf, fs.fileMode)
+	}
+	if err != nil {
+		fs.dirty = true
 	}
 	return
 }
 
+// Load
+//
+// Loads the file into the bound object. The changes are reported to the watchers as one OpSet change,
+// and nothing is reported if the loaded content is the same as the bound object.
+//
+// If the loading fails, or the content is rejected by the validate (see SetValidate), the bound object stays untouched.
 func (fs *FileSyncer) Load() (err error) {
-	fs.objMutex.Lock()
-	if fs.obj == nil {
-		fs.objMutex.Unlock()
+	var (
+		buf       []byte
+		boundObj  *Object
+		formatter Formatter
+		hardLoad  bool
+		validate  func(candidate *Object) error
+	)
+	fs.fileMutex.Lock()
+	boundObj = fs.obj
+	formatter = fs.formatter
+	hardLoad = fs.hardLoad
+	validate = fs.validate
+	if boundObj == nil {
+		fs.fileMutex.Unlock()
 		return noBoundObjErr{}
-	} else {
-		fs.objMutex.Unlock()
 	}
-	var buf []byte
-	fs.fileMutex.Lock()
-	buf, err = ioutil.ReadFile(fs.filePath)
+	filePath := fs.filePath
+	var path string
+	path, err = expandPath(filePath)
+	var info os.FileInfo
+	if err == nil {
+		buf, info, err = readFileInfo(path)
+	}
 	fs.fileMutex.Unlock()
 	if err == nil {
 		var obj *Object
-		obj, err = fs.formatter.Unmarshal(buf)
+		obj, err = formatter.Unmarshal(buf)
 		if err == nil {
-			if fs.HardLoad {
-				fs.objMutex.Lock()
-				fs.obj.setVal(obj, false)
-				fs.objMutex.Unlock()
-			} else {
-				fs.objMutex.Lock()
-				err = fs.obj.groupMerge(obj, true, false)
-				fs.objMutex.Unlock()
-			}
+			// the loading is made on a clone and applied at once, a failed loading never leaves the bound object half-replaced
+			// the change is made with fs as the source, which is not saved back by the listener (see BindObject)
+			err = boundObj.batch(fs, func(tx *Object) error {
+				if hardLoad {
+					tx.SetVal(obj)
+				} else if err := tx.GroupMerge(obj, true); err != nil {
+					return err
+				}
+				if validate != nil {
+					return validate(tx)
+				}
+				return nil
+			})
 		}
 	}
+	if err == nil {
+		// only the file loaded successfully is recorded, a failed one is tried again by the next auto loading
+		fs.fileMutex.Lock()
+		if fs.obj == boundObj && fs.filePath == filePath && fs.hardLoad == hardLoad {
+			fs.setStamp(info, buf)
+		}
+		fs.fileMutex.Unlock()
+	}
+	return
+}
+
+// reload
+//
+// Loads the file only if it is changed since the last loading.
+func (fs *FileSyncer) reload() error {
+	changed, err := fs.fileChanged()
+	if err != nil || !changed {
+		return err
+	}
+	return fs.Load()
+}
+
+// fileChanged
+//
+// Checks the modification time and the size of the file first, and then the content hash.
+func (fs *FileSyncer) fileChanged() (changed bool, err error) {
+	fs.fileMutex.Lock()
+	defer fs.fileMutex.Unlock()
+	path, err := expandPath(fs.filePath)
+	if err != nil {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	if fs.stamp.valid && info.ModTime().Equal(fs.stamp.modTime) && info.Size() == fs.stamp.size {
+		return false, nil
+	}
+	buf, info, err := readFileInfo(path)
+	if err != nil {
+		return
+	}
+	if hash := sha256.Sum256(buf); fs.stamp.valid && bytes.Equal(hash[:], fs.stamp.hash[:]) {
+		// only touched, the new modification time is kept to skip the reading next time
+		fs.stamp.modTime, fs.stamp.size = info.ModTime(), info.Size()
+		return false, nil
+	}
+	return true, nil
+}
+
+// setStamp
+//
+// Records the file just loaded, must be called with fileMutex locked.
+func (fs *FileSyncer) setStamp(info os.FileInfo, content []byte) {
+	fs.stamp = fileStamp{
+		valid:   true,
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		hash:    sha256.Sum256(content),
+	}
+}
+
+// readFileInfo
+//
+// Reads the file and stats it by the same opened file, the stat is taken before reading.
+// So a replacing after the stat makes the stamp out of date (and reloads later), but never pairs a new stat with the old content.
+func readFileInfo(path string) (content []byte, info os.FileInfo, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	info, err = f.Stat()
+	if err != nil {
+		return
+	}
+	content, err = ioutil.ReadAll(f)
 	return
 }
 
@@ -134,57 +587,298 @@ func (fs *FileSyncer) BindObject(obj *Object) {
 	if obj == nil || !obj.IsGroup() {
 		panic(invalidTypeErr(""))
 	}
-	fs.objMutex.Lock()
-	defer fs.objMutex.Unlock()
-	if fs.obj != nil {
-		fs.obj.onChange = nil
+	fs.fileMutex.Lock()
+	defer fs.fileMutex.Unlock()
+	if fs.unwatch != nil {
+		fs.unwatch()
 	}
+	fs.stopDebounce()
 	fs.obj = obj
-	fs.obj.onChange = func() {
-		if fs.AutoLoadTiming <= 0 && fs.AutoSaveTiming == 0 {
-			_ = fs.Save()
+	// the newly bound object is not saved yet
+	fs.dirty = true
+	fs.unwatch, _ = obj.Watch("", func(ev ChangeEvent) {
+		if ev.source == fs {
+			return
+		}
+		fs.fileMutex.Lock()
+		if fs.obj != obj {
+			fs.fileMutex.Unlock()
+			return
+		}
+		fs.dirty = true
+		saveNow := fs.autoLoadTiming <= 0 && fs.autoSaveTiming == 0
+		if saveNow && fs.saveDebounce > 0 {
+			fs.debounceSave()
+			saveNow = false
 		}
+		fs.fileMutex.Unlock()
+		if saveNow {
+			fs.reportErr("save", fs.saveIfDirty())
+		}
+	})
+}
+
+// debounceSave
+//
+// Delays the saving until no change is made in saveDebounce milliseconds, or saveMaxWait milliseconds are passed
+// since the first unsaved change. Must be called with fileMutex locked.
+func (fs *FileSyncer) debounceSave() {
+	now := time.Now()
+	if fs.saveTimer == nil {
+		fs.saveFirst = now
+	} else {
+		fs.saveTimer.Stop()
 	}
+	delay := time.Duration(fs.saveDebounce) * time.Millisecond
+	if fs.saveMaxWait > 0 {
+		if d := fs.saveFirst.Add(time.Duration(fs.saveMaxWait) * time.Millisecond).Sub(now); d < delay {
+			delay = d
+		}
+	}
+	// the stopped timer may be firing, it is ignored by the id
+	fs.saveTimerID++
+	id := fs.saveTimerID
+	fs.saveTimer = time.AfterFunc(delay, func() {
+		fs.fileMutex.Lock()
+		if id != fs.saveTimerID {
+			fs.fileMutex.Unlock()
+			return
+		}
+		fs.saveTimer = nil
+		fs.fileMutex.Unlock()
+		fs.reportErr("save", fs.saveIfDirty())
+	})
+}
+
+// stopDebounce
+//
+// Cancels the debounced saving, must be called with fileMutex locked.
+func (fs *FileSyncer) stopDebounce() {
+	if fs.saveTimer != nil {
+		fs.saveTimer.Stop()
+		fs.saveTimer = nil
+	}
+	fs.saveTimerID++
+}
+
+// Start
+//
+// Starts the timers of auto saving and auto loading, they are stopped when the ctx is done or Close is called.
+// Returns an error wrapping ErrSyncerStarted if it is called twice, or ErrSyncerClosed after Close.
+//
+// Auto saving on changes (AutoSaveTiming == 0) works after BindObject, without Start.
+func (fs *FileSyncer) Start(ctx context.Context) error {
+	fs.fileMutex.Lock()
+	defer fs.fileMutex.Unlock()
+	if fs.closed {
+		return syncerClosedErr{}
+	}
+	if fs.done != nil {
+		return syncerStartedErr{}
+	}
+	fs.done = make(chan struct{})
+	go fs.run(ctx, fs.done)
+	return nil
+}
+
+// run
+//
+// The timer loop started by Start.
+func (fs *FileSyncer) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	var notifier *fileNotifier
+	defer func() {
+		if notifier != nil {
+			notifier.close()
+		}
+	}()
+	for {
+		fs.fileMutex.Lock()
+		autoLoadTiming := fs.autoLoadTiming
+		autoSaveTiming := fs.autoSaveTiming
+		notify := fs.notify
+		path, _ := expandPath(fs.filePath)
+		fs.fileMutex.Unlock()
+		var (
+			timer   *time.Timer
+			tick    <-chan time.Time
+			changed <-chan struct{}
+		)
+		if autoLoadTiming > 0 && notify {
+			if notifier != nil && notifier.path != path {
+				notifier.close()
+				notifier = nil
+			}
+			if notifier == nil {
+				// falls back to the timer if the notifications are not supported
+				notifier, _ = newFileNotifier(path)
+			}
+		} else if notifier != nil {
+			notifier.close()
+			notifier = nil
+		}
+		if notifier != nil {
+			changed = notifier.changed
+		}
+		if autoLoadTiming > 0 {
+			timer = time.NewTimer(time.Duration(autoLoadTiming) * time.Millisecond)
+		} else if autoSaveTiming > 0 {
+			timer = time.NewTimer(time.Duration(autoSaveTiming) * time.Millisecond)
+		}
+		if timer != nil {
+			tick = timer.C
+		}
+		select {
+		case <-tick:
+			if autoLoadTiming > 0 {
+				fs.reportErr("load", fs.reload())
+			} else {
+				fs.reportErr("save", fs.saveIfDirty())
+			}
+			continue
+		case <-changed:
+			fs.reportErr("load", fs.reload())
+		case <-fs.wake:
+		case <-fs.stop:
+		case <-ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		select {
+		case <-fs.stop:
+			return
+		case <-ctx.Done():
+			return
+		default:
+		}
+	}
+}
+
+// Close
+//
+// Stops the timers started by Start and the debounced saving, unbinds the listener on the bound object, and saves the changes not saved yet (unless auto saving is disabled).
+// Returns the error of the saving. The FileSyncer can not be started again, but Save and Load still work.
+func (fs *FileSyncer) Close() (err error) {
+	fs.fileMutex.Lock()
+	if fs.closed {
+		fs.fileMutex.Unlock()
+		return nil
+	}
+	fs.closed = true
+	if fs.stop != nil {
+		close(fs.stop)
+	}
+	done := fs.done
+	if fs.unwatch != nil {
+		fs.unwatch()
+		fs.unwatch = nil
+	}
+	fs.stopDebounce()
+	flush := fs.dirty && fs.autoLoadTiming <= 0 && fs.autoSaveTiming >= 0
+	fs.fileMutex.Unlock()
+	if done != nil {
+		<-done
+	}
+	if flush {
+		err = fs.Save()
+	}
+	return
 }
 
 // NewFileSyncer
 //
-// Creates a new FileSyncer with filePath and formatter.
+// Creates a new FileSyncer with filePath, formatter and options.
 //
 // To enable the FileSyncer, Follow the steps below :
 //
-//    1. Call NewFileSyncer (set filePath and formatter).
-//    2. Set some options such as AutoSaveTiming, AutoLoadTiming, HardLoad, etc...
-//       The filePath and formatter can be set as well but using `Set` method.
-//    3. Call FileSyncer.BindObject to bind the object that to be synced.
-//    4. Then you can see the FileSyncer starts working automatically.
-//    5. You can also call FileSyncer.Save or FileSyncer.Load to sync manually.
-func NewFileSyncer(filePath string, formatter Formatter) *FileSyncer {
+//    1. Call NewFileSyncer (set filePath, formatter and options such as WithAutoSaveTiming, WithAutoLoadTiming, WithHardLoad, etc...).
+//       The options can be set later as well but using `Set` method.
+//    2. Call FileSyncer.BindObject to bind the object that to be synced.
+//    3. Call FileSyncer.Start to start the timers of auto saving and auto loading.
+//    4. You can also call FileSyncer.Save or FileSyncer.Load to sync manually.
+//    5. Call FileSyncer.Close to stop it, the pending changes are saved.
+func NewFileSyncer(filePath string, formatter Formatter, opts ...FileSyncerOption) *FileSyncer {
 	fs := &FileSyncer{
 		filePath:  filePath,
+		fileMode:  0644,
 		formatter: formatter,
-		HardLoad:  false,
+		hardLoad:  false,
 		obj:       nil,
+		wake:      make(chan struct{}, 1),
+		stop:      make(chan struct{}),
 	}
-	go func() {
-		for {
-			func() {
-				defer func() {
-					_ = recover()
-				}()
-				autoLoadTiming := fs.AutoLoadTiming
-				autoSaveTiming := fs.AutoSaveTiming
-				if autoLoadTiming > 0 {
-					time.Sleep(time.Duration(autoLoadTiming * int64(time.Millisecond)))
-					_ = fs.Load()
-				} else if autoSaveTiming > 0 {
-					time.Sleep(time.Duration(autoSaveTiming * int64(time.Millisecond)))
-					_ = fs.Save()
-				} else {
-					time.Sleep(time.Second)
-				}
-			}()
+	for _, opt := range opts {
+		opt(fs)
+	}
+	return fs
+}
+
+// expandPath
+//
+// Expands the leading `~` of a path to the home directory of the current user.
+func expandPath(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !(runtime.GOOS == "windows" && strings.HasPrefix(path, `~\`)) {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, path[1:]), nil
+}
+
+// writeFileAtomic
+//
+// Writes data to a temp file in the same directory, and renames it over the file. So the file is never seen half-written,
+// even if the process crashes or the disk is full.
+func writeFileAtomic(path string, data []byte, mode os.FileMode) (err error) {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := ioutil.TempFile(dir, "."+name+".tmp*")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
 		}
 	}()
-	return fs
+	if _, err = tmp.Write(data); err != nil {
+		return
+	}
+	if err = tmp.Chmod(mode); err != nil {
+		return
+	}
+	if err = tmp.Sync(); err != nil {
+		return
+	}
+	if err = tmp.Close(); err != nil {
+		return
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return
+	}
+	return syncDir(dir)
+}
+
+// syncDir
+//
+// Flushes the renaming in the directory to the disk. Directories can not be synced on Windows, where it does nothing.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if closeErr := d.Close(); err == nil {
+		err = closeErr
+	}
+	return err
 }
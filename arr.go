@@ -1,59 +1,59 @@
 package m2obj
 
-import (
-	"regexp"
-	"strconv"
-)
-
-// arrCheckIndexFormat
-//
-// Likes *Object.arrCheckIndexKey but only match the format, no verifying on integer transform, no index overflow checking.
-func arrCheckIndexFormat(key string) bool {
-	reg := regexp.MustCompile(`\[(\d+)]`)
-	return reg.MatchString(key)
-}
-
 // arrCheckIndexKey
 //
 // To get an element by index of an Array Object, the keyStr Must be formatted as this:
 //     xxx.ArrayName.[index].xxx
 // It means that there must be an index statement quoted with '[' and ']' after an Array Object.
 //
+// A quoted or escaped key is never an index. A negative index counts from the end of the Array, such as `[-1]` for the last element.
+//
+// The `[+]` key is always overflowed here, it only makes sense when pushing.
+//
 // This func checks off the rule above.
-func (o *Object) arrCheckIndexKey(key, keyStr string) (index int, err error) {
-	reg := regexp.MustCompile(`\[(\d+)]`)
-
-	if !reg.MatchString(key) { // the key doesn't be matched as [number]
-		err = invalidTypeErr(keyStr)
+func (o *Object) arrCheckIndexKey(item keyItem) (index int, err error) {
+	arr := *o.val.(*arrayData)
+	if item.isPush { // the position behind the last element
+		err = indexOverflowErr{
+			Index: len(arr),
+		}
 		return
-	} else { // matched
-		index, err = strconv.Atoi(reg.FindStringSubmatch(key)[1])
-		if err != nil { // the key can not trans to an Integer
-			err = invalidKeyStrErr(keyStr)
-			return
-		} else { // gotten an integer as the index
-			arr := *o.val.(*arrayData)
-			if index < 0 || len(arr) <= index { // the index overflows from the arr
-				err = indexOverflowErr{
-					Index: index,
-				}
-				return
-			} else { // no error, check passed
-				return index, nil
-			}
+	}
+	if !item.isIndex { // the key doesn't be matched as [number]
+		err = invalidTypeErr("")
+		return
+	}
+	index = item.index
+	if index < 0 { // count from the end
+		index += len(arr)
+	}
+	if index < 0 || len(arr) <= index { // the index overflows from the arr
+		err = indexOverflowErr{
+			Index: item.index,
 		}
+		return
 	}
+	// no error, check passed
+	return index, nil
 }
 
 // ArrPush **!!! ONLY FOR ARR OBJECT**
 //
 // Push a value (or an Object) back into the Array Object.
 func (o *Object) ArrPush(value interface{}) {
+	value = detachValue(value)
+	o.mustWrite(func() {
+		o.arrPush(value)
+	})
+}
+
+func (o *Object) arrPush(value interface{}) {
 	switch o.val.(type) {
 	case *arrayData:
+		c := o.beginChange([]keyItem{indexKeyItem(o.arrLen())}, OpInsert, nil)
 		*o.val.(*arrayData) = append(*o.val.(*arrayData), New(value))
-		o.ArrGet(o.ArrLen() - 1).buildParentLink(o)
-		o.callOnChange()
+		o.arrGet(o.arrLen() - 1).buildParentLink(o)
+		c.end(o.arrGet(o.arrLen() - 1))
 	default:
 		panic(invalidTypeErr(""))
 	}
@@ -63,14 +63,17 @@ func (o *Object) ArrPush(value interface{}) {
 //
 // Pop back from the Array Object.
 func (o *Object) ArrPop() (value *Object) {
-	switch o.val.(type) {
-	case *arrayData:
-		value = o.ArrGet(o.ArrLen() - 1)
-		*o.val.(*arrayData) = (*o.val.(*arrayData))[:len(*o.val.(*arrayData))-1]
-		o.callOnChange()
-	default:
-		panic(invalidTypeErr(""))
-	}
+	o.mustWrite(func() {
+		switch o.val.(type) {
+		case *arrayData:
+			value = o.arrGet(o.arrLen() - 1)
+			c := o.beginChange([]keyItem{indexKeyItem(o.arrLen() - 1)}, OpRemove, value)
+			*o.val.(*arrayData) = (*o.val.(*arrayData))[:len(*o.val.(*arrayData))-1]
+			c.end(nil)
+		default:
+			panic(invalidTypeErr(""))
+		}
+	})
 	return
 }
 
@@ -78,20 +81,29 @@ func (o *Object) ArrPop() (value *Object) {
 //
 // Set the value of the element which indexed at the Array Object.
 func (o *Object) ArrSet(index int, value interface{}) {
-	switch o.val.(type) {
-	case *arrayData:
-		(*o.val.(*arrayData))[index] = New(value)
-		o.ArrGet(index).buildParentLink(o)
-		o.callOnChange()
-	default:
-		panic(invalidTypeErr(""))
-	}
+	value = detachValue(value)
+	o.mustWrite(func() {
+		switch o.val.(type) {
+		case *arrayData:
+			c := o.beginChange([]keyItem{indexKeyItem(index)}, OpSet, o.arrGet(index))
+			(*o.val.(*arrayData))[index] = New(value)
+			o.arrGet(index).buildParentLink(o)
+			c.end(o.arrGet(index))
+		default:
+			panic(invalidTypeErr(""))
+		}
+	})
 }
 
 // ArrGet **!!! ONLY FOR ARR OBJECT**
 //
 // An alias of `MustGet("[index]")`
 func (o *Object) ArrGet(index int) (obj *Object) {
+	defer o.rLock()()
+	return o.arrGet(index)
+}
+
+func (o *Object) arrGet(index int) (obj *Object) {
 	switch o.val.(type) {
 	case *arrayData:
 		return (*o.val.(*arrayData))[index]
@@ -106,6 +118,13 @@ func (o *Object) ArrGet(index int) (obj *Object) {
 //
 // Specially, if `index == o.ArrLen()` , this is same as `o.Push(value)` but has a lower performance.
 func (o *Object) ArrInsert(index int, value interface{}) {
+	value = detachValue(value)
+	o.mustWrite(func() {
+		o.arrInsert(index, value)
+	})
+}
+
+func (o *Object) arrInsert(index int, value interface{}) {
 	switch o.val.(type) {
 	case *arrayData:
 		var (
@@ -127,11 +146,12 @@ func (o *Object) ArrInsert(index int, value interface{}) {
 			arrAfter = append(arrAfter, arr[index:]...)
 		}
 		// generate
+		c := o.beginChange([]keyItem{indexKeyItem(index)}, OpInsert, nil)
 		arrRes = append(arrBefore, New(value))
 		arrRes = append(arrRes, arrAfter...)
 		*o.val.(*arrayData) = arrRes
-		o.ArrGet(index).buildParentLink(o)
-		o.callOnChange()
+		o.arrGet(index).buildParentLink(o)
+		c.end(o.arrGet(index))
 	default:
 		panic(invalidTypeErr(""))
 	}
@@ -141,6 +161,12 @@ func (o *Object) ArrInsert(index int, value interface{}) {
 //
 // Remove the element which indexed at the Array Object.
 func (o *Object) ArrRemove(index int) {
+	o.mustWrite(func() {
+		o.arrRemove(index)
+	})
+}
+
+func (o *Object) arrRemove(index int) {
 	switch o.val.(type) {
 	case *arrayData:
 		var (
@@ -162,9 +188,10 @@ func (o *Object) ArrRemove(index int) {
 			arrAfter = append(arrAfter, arr[index+1:]...)
 		}
 		// generate
+		c := o.beginChange([]keyItem{indexKeyItem(index)}, OpRemove, arr[index])
 		arrRes = append(arrBefore, arrAfter...)
 		*o.val.(*arrayData) = arrRes
-		o.callOnChange()
+		c.end(nil)
 	default:
 		panic(invalidTypeErr(""))
 	}
@@ -189,17 +216,23 @@ func (o *Object) ArrUnshift(value interface{}) {
 // Loop for range `[0...o.ArrLen()-1]`, foreach calls `do`.
 //
 // Stops when do returns a non-nil err and return it.
+//
+// The elements are taken before looping, so it is safe to change the array in `do`.
 func (o *Object) ArrForeach(do func(index int, obj *Object) error) (err error) {
-	switch o.val.(type) {
-	case *arrayData:
-		for i, obj := range *o.val.(*arrayData) {
-			if err = do(i, obj); err != nil {
-				break
-			}
+	var arr arrayData
+	func() {
+		defer o.rLock()()
+		switch o.val.(type) {
+		case *arrayData:
+			arr = append(arrayData{}, *o.val.(*arrayData)...)
+		default:
+			panic(invalidTypeErr(""))
+		}
+	}()
+	for i, obj := range arr {
+		if err = do(i, obj); err != nil {
+			break
 		}
-		o.buildParentLink(o.parent)
-	default:
-		panic(invalidTypeErr(""))
 	}
 	return
 }
@@ -208,44 +241,44 @@ func (o *Object) ArrForeach(do func(index int, obj *Object) error) (err error) {
 //
 // Push all of elements from an Array Object o2 into the Array Object
 func (o *Object) ArrMerge(o2 *Object) {
-	switch o.val.(type) {
-	case *arrayData:
-		switch o2.val.(type) {
-		case *arrayData: // Group
-			newArr := o.Clone()
-			err := o2.ArrForeach(func(index int, obj *Object) error {
-				newArr.ArrPush(obj)
-				return nil
-			})
-			if err == nil {
-				o.SetVal(newArr)
+	// take a snapshot of o2 first, o2 may be in the same tree with o.
+	o2 = o2.Clone()
+	o.mustWrite(func() {
+		switch o.val.(type) {
+		case *arrayData:
+			switch o2.val.(type) {
+			case *arrayData: // Array
+				for _, obj := range *o2.val.(*arrayData) {
+					c := o.beginChange([]keyItem{indexKeyItem(o.arrLen())}, OpInsert, nil)
+					*o.val.(*arrayData) = append(*o.val.(*arrayData), obj)
+					c.end(obj)
+				}
 				o.buildParentLink(o.parent)
+			default:
+				panic(invalidTypeErr(""))
 			}
 		default:
 			panic(invalidTypeErr(""))
 		}
-	default:
-		panic(invalidTypeErr(""))
-	}
+	})
 }
 
 // ArrPushAll **!!! ONLY FOR ARR OBJECT**
 //
 // Push all the elements in the parameter to the array object
 func (o *Object) ArrPushAll(values ...interface{}) {
-	switch o.val.(type) {
-	case *arrayData:
-		o2 := New(Array(values))
-		o.ArrMerge(o2)
-	default:
-		panic(invalidTypeErr(""))
-	}
+	o.ArrMerge(New(Array(values)))
 }
 
 // ArrLen **!!! ONLY FOR ARR OBJECT**
 //
 // Get the length of an array object
 func (o *Object) ArrLen() int {
+	defer o.rLock()()
+	return o.arrLen()
+}
+
+func (o *Object) arrLen() int {
 	switch o.val.(type) {
 	case *arrayData:
 		return len(*o.val.(*arrayData))
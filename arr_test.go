@@ -1,7 +1,9 @@
 package m2obj
 
 import (
+	"errors"
 	"github.com/stretchr/testify/assert"
+	"strconv"
 	"testing"
 )
 
@@ -226,7 +228,7 @@ func TestObject_ArrForeach(t *testing.T) {
 		arr3 []int
 	)
 	arr1 = New(Array{1, 2, 3})
-	arr2 = New(Array{1, 2, "3"})
+	arr2 = New(Array{1, 2, "three"})
 	arr3 = make([]int, 0)
 	// no error and no panic
 	assert.NotPanics(t, func() {
@@ -259,9 +261,115 @@ func TestObject_ArrForeach(t *testing.T) {
 		}))
 	})
 	assert.Equal(t, []int{1, 2}, arr3)
+	assert.NotPanics(t, func() { // a typed slice is an Array
+		assert.NoError(t, New(arr3).ArrForeach(func(index int, obj *Object) error {
+			return nil
+		}))
+	})
 	assert.Panics(t, func() {
-		New(arr3).ArrForeach(func(index int, obj *Object) error {
+		New("not an array").ArrForeach(func(index int, obj *Object) error {
 			return nil
 		})
 	})
 }
+
+func TestObject_ArrKeyStr(t *testing.T) {
+	obj := New(Group{
+		"servers": Array{"a", "b", "c"},
+	})
+	// negative index
+	assert.Equal(t, "c", obj.MustGet("servers.[-1]").ValStr())
+	assert.Equal(t, "a", obj.MustGet("servers.[-3]").ValStr())
+	assert.True(t, obj.Has("servers.[-3]"))
+	assert.False(t, obj.Has("servers.[-4]"))
+	var pathErr *PathError
+	_, err := obj.Get("servers.[-4]")
+	assert.True(t, errors.As(err, &pathErr))
+	assert.Equal(t, indexOverflowErr{Index: -4}, pathErr.Err)
+	_, err = obj.Get("servers.[3]")
+	assert.True(t, errors.As(err, &pathErr))
+	assert.Equal(t, indexOverflowErr{Index: 3}, pathErr.Err)
+	assert.NoError(t, obj.Set("servers.[-2]", "B"))
+	assert.True(t, errors.Is(obj.Set("servers.[-4]", "x"), ErrIndexOverflow))
+	// push
+	_, err = obj.Get("servers.[+]")
+	assert.True(t, errors.As(err, &pathErr))
+	assert.Equal(t, indexOverflowErr{Index: 3}, pathErr.Err)
+	assert.False(t, obj.Has("servers.[]"))
+	assert.NoError(t, obj.Set("servers.[+]", "d"))
+	assert.NoError(t, obj.Set("servers.[]", "e"))
+	assert.NoError(t, obj.Set("servers.[+].name", "f"))
+	assert.Equal(t, "f", obj.MustGet("servers.[-1].name").ValStr())
+	assert.Equal(t, obj.MustGet("servers"), obj.MustGet("servers.[-1]").Parent())
+	assert.Equal(t, map[string]interface{}{
+		"servers": []interface{}{
+			"a", "B", "c", "d", "e",
+			map[string]interface{}{
+				"name": "f",
+			},
+		},
+	}, obj.Staticize())
+	// remove through a negative index
+	assert.True(t, obj.Remove("servers.[-1].name"))
+	assert.Equal(t, map[string]interface{}{}, obj.MustGet("servers.[-1]").Staticize())
+}
+
+func TestObject_ArrAutoCreate(t *testing.T) {
+	obj := New(Group{})
+	assert.NoError(t, obj.Set("a.list.[0].name", "x"))
+	assert.NoError(t, obj.Set("a.list.[+].[+]", "y"))
+	assert.NoError(t, obj.Set("a.list.[3].name", "z"))
+	assert.NoError(t, obj.Set("a.list.[2].[1]", "w"))
+	assert.NoError(t, obj.Set("b.[1]", 1))
+	assert.Equal(t, map[string]interface{}{
+		"a": map[string]interface{}{
+			"list": []interface{}{
+				map[string]interface{}{
+					"name": "x",
+				},
+				[]interface{}{"y"},
+				[]interface{}{nil, "w"},
+				map[string]interface{}{
+					"name": "z",
+				},
+			},
+		},
+		"b": []interface{}{nil, 1},
+	}, obj.Staticize())
+	// the gap to fill is limited
+	before := obj.Staticize()
+	var pathErr *PathError
+	err := obj.Set("b.[1000000000]", 1)
+	assert.True(t, errors.As(err, &pathErr))
+	assert.Equal(t, indexOverflowErr{Index: 1000000000}, pathErr.Err)
+	assert.True(t, errors.Is(obj.Set("c.[1000000000].d", 1), ErrIndexOverflow))
+	assert.Equal(t, before, obj.Staticize())
+	assert.NoError(t, obj.Set("c.["+strconv.Itoa(maxArrGap)+"]", 1))
+	assert.Equal(t, maxArrGap+1, obj.MustGet("c").ArrLen())
+	assert.True(t, obj.Remove("c"))
+	list := obj.MustGet("a.list")
+	assert.NoError(t, list.ArrForeach(func(index int, elem *Object) error {
+		assert.Equal(t, list, elem.Parent())
+		return nil
+	}))
+	assert.Equal(t, list.MustGet("[2]"), list.MustGet("[2].[1]").Parent())
+	// a flat list of paths can be turned back into a tree in any order
+	flat := map[string]interface{}{
+		"servers.[1].host":      "b",
+		"servers.[1].ports.[1]": 81,
+		"servers.[0].host":      "a",
+		"servers.[1].ports.[0]": 80,
+	}
+	obj2 := New(Group{})
+	for keyStr, v := range flat {
+		assert.NoError(t, obj2.Set(keyStr, v))
+	}
+	for keyStr, v := range flat {
+		assert.Equal(t, v, obj2.MustGet(keyStr).Val())
+	}
+	// still invalid
+	assert.Error(t, obj.Set("a.list.[1].x", 0))
+	assert.Error(t, obj.Set("a.list.[-9]", 0))
+	_, err = obj.Get("a.list.[4]")
+	assert.True(t, errors.Is(err, ErrIndexOverflow))
+}
@@ -0,0 +1,117 @@
+package m2obj
+
+import "reflect"
+
+// Batch
+//
+// Applies many mutations as one. The fn is called with tx, a clone of the object, and all of the mutations should be made on tx.
+// When fn returns nil, the changes of tx are committed into the object in place and reported to the watchers as one OpSet change of o,
+// so a bound FileSyncer saves only once. When fn returns an error (or panics), nothing is changed and the error is returned.
+//
+// The object tree is locked for writing until fn returns, so that no other changes can be lost by the committing.
+// Therefore fn must not access the object tree itself (it deadlocks), and tx must not be used after fn returns.
+//
+// Example:
+//
+//   err := obj.Batch(func(tx *m2obj.Object) error {
+//     for i, name := range names {
+//       if err := tx.Set("users.[+].name", name); err != nil {
+//         return err
+//       }
+//     }
+//     return tx.Set("users.count", len(names))
+//   })
+func (o *Object) Batch(fn func(tx *Object) error) (err error) {
+	return o.batch(nil, fn)
+}
+
+// batch
+//
+// Likes Batch, and the source is set to the ChangeEvents queued by the committing, so the listener can tell the changes made by itself.
+func (o *Object) batch(source interface{}, fn func(tx *Object) error) (err error) {
+	return o.write(func() {
+		t := o.getTree()
+		t.source = source
+		defer func() {
+			t.source = nil
+		}()
+		tx := o.clone()
+		tx.tree.strict = o.isStrict()
+		if err := fn(tx); err != nil {
+			panic(err)
+		}
+		c := o.beginChange(nil, OpSet, o)
+		if o.syncTo(tx) {
+			o.buildParentLink(o.parent)
+			c.end(o)
+		}
+	})
+}
+
+// syncTo
+//
+// Makes o equal to src in place, the children of o are kept if they are still in src with the same type,
+// and the values equal to the ones in src as JSON are kept as well. Returns if o is changed.
+//
+// The children of src may be moved into o, rebuild the parent links after it.
+func (o *Object) syncTo(src *Object) (changed bool) {
+	switch srcVal := src.valOrNil().(type) {
+	case *groupData:
+		grp, ok := o.val.(*groupData)
+		if !ok {
+			grp = &groupData{}
+			o.val = grp
+			changed = true
+		}
+		for k := range *grp {
+			if _, ok := (*srcVal)[k]; !ok {
+				delete(*grp, k)
+				changed = true
+			}
+		}
+		for k, srcObj := range *srcVal {
+			obj, ok := (*grp)[k]
+			if ok && obj != nil && srcObj != nil {
+				changed = obj.syncTo(srcObj) || changed
+			} else if !ok || obj != srcObj {
+				(*grp)[k] = srcObj
+				changed = true
+			}
+		}
+	case *arrayData:
+		arr, ok := o.val.(*arrayData)
+		if !ok {
+			arr = &arrayData{}
+			o.val = arr
+			changed = true
+		}
+		for i, srcObj := range *srcVal {
+			if i >= len(*arr) {
+				*arr = append(*arr, srcObj)
+				changed = true
+			} else if obj := (*arr)[i]; obj != nil && srcObj != nil {
+				changed = obj.syncTo(srcObj) || changed
+			} else if obj != srcObj {
+				(*arr)[i] = srcObj
+				changed = true
+			}
+		}
+		if len(*arr) > len(*srcVal) {
+			*arr = (*arr)[:len(*srcVal)]
+			changed = true
+		}
+	default:
+		switch o.val.(type) {
+		case *groupData, *arrayData:
+			o.val = srcVal
+			changed = true
+		default:
+			// the values equal as JSON (e.g. 8080 and 8080.0 from a loaded file) are kept with their types
+			if !reflect.DeepEqual(o.val, srcVal) && !jsonEqual(o.val, srcVal) {
+				o.val = srcVal
+				changed = true
+			}
+		}
+	}
+	return
+}
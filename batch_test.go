@@ -0,0 +1,84 @@
+package m2obj
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObject_Batch(t *testing.T) {
+	obj := New(Group{
+		"db": Group{
+			"name": "main",
+		},
+		"servers": Array{"s0", Group{"name": "s1"}, "s2"},
+		"removed": true,
+	})
+	db := obj.MustGet("db")
+	s1 := obj.MustGet("servers.[1]")
+	var events []ChangeEvent
+	_, err := obj.Watch("", func(ev ChangeEvent) {
+		events = append(events, ev)
+	})
+	assert.NoError(t, err)
+
+	// commit
+	assert.NoError(t, obj.Batch(func(tx *Object) error {
+		for i := 0; i < 50; i++ {
+			if err := tx.Set("keys.k"+strconv.Itoa(i), i); err != nil {
+				return err
+			}
+		}
+		tx.MustGet("servers").ArrPop()
+		assert.NoError(t, tx.Set("servers.[1].port", 80))
+		assert.True(t, tx.Remove("removed"))
+		return tx.Set("db.name", "backup")
+	}))
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, OpSet, events[0].Op)
+	assert.Equal(t, obj.Staticize(), events[0].New)
+	assert.Equal(t, 50, len(obj.MustGet("keys").Staticize()))
+	assert.Equal(t, map[string]interface{}{"name": "s1", "port": 80}, obj.MustGet("servers.[1]").Staticize())
+	assert.Equal(t, 2, obj.MustGet("servers").ArrLen())
+	assert.False(t, obj.Has("removed"))
+	assert.Equal(t, "backup", db.MustGet("name").ValStr())
+	// the kept children are still valid
+	assert.Equal(t, db, obj.MustGet("db"))
+	assert.Equal(t, s1, obj.MustGet("servers.[1]"))
+	assert.Equal(t, obj.MustGet("keys"), obj.MustGet("keys.k0").Parent())
+
+	// rollback
+	before := obj.Staticize()
+	errTest := errors.New("test")
+	assert.Equal(t, errTest, obj.Batch(func(tx *Object) error {
+		assert.NoError(t, tx.Set("db.name", "x"))
+		assert.True(t, tx.Remove("keys"))
+		return errTest
+	}))
+	assert.Error(t, obj.Batch(func(tx *Object) error {
+		assert.NoError(t, tx.Set("db.name", "x"))
+		tx.MustGet("db").ArrPush(1) // panics
+		return nil
+	}))
+	assert.Equal(t, before, obj.Staticize())
+	// no change
+	assert.NoError(t, obj.Batch(func(tx *Object) error {
+		return tx.Set("db.name", "backup")
+	}))
+	assert.Equal(t, 1, len(events))
+
+	// a child
+	assert.NoError(t, db.Batch(func(tx *Object) error {
+		tx.SetVal(Array{1})
+		return nil
+	}))
+	assert.Equal(t, []interface{}{1}, obj.MustGet("db").Staticize()["list"])
+	assert.Equal(t, ChangeEvent{
+		KeyStr: "db",
+		Op:     OpSet,
+		Old:    map[string]interface{}{"name": "backup"},
+		New:    []interface{}{1},
+	}, events[1])
+}
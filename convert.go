@@ -0,0 +1,444 @@
+package m2obj
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The conversion layer used by all the ValXxx methods.
+//
+// Instead of reflect.Value.Convert, the values are converted by their meaning:
+//   Numbers are formatted as decimal strings, and numeric strings are parsed by strconv.
+//   Bools accept "true"/"yes"/"on"/"1" and "false"/"no"/"off"/"0" (case-insensitive).
+//   Floats are truncated when converted to integers, and integers are wrapped when overflowing, like the Go conversions.
+// In the strict mode (see Object.SetStrict), the lossy conversions (a float with fraction to an integer, an overflowing number, etc.) are rejected.
+//
+// All of the toXxx funcs return a convertErr when the value can not be converted.
+
+// newConvertErr
+//
+// Creates a convertErr for converting v to the type named to.
+func newConvertErr(v interface{}, to string, reason string) convertErr {
+	return convertErr{
+		From:   fmt.Sprintf("%T", v),
+		To:     to,
+		Reason: reason,
+	}
+}
+
+// newListConvertErr
+//
+// Creates a convertErr for converting the i-th element of the list v failed with err.
+func newListConvertErr(v interface{}, to string, i int, err error) convertErr {
+	return newConvertErr(v, to, "the element ["+strconv.Itoa(i)+"]: "+err.Error())
+}
+
+func toString(v interface{}) (string, error) {
+	switch tv := v.(type) {
+	case string:
+		return tv, nil
+	case []byte:
+		return string(tv), nil
+	case []rune:
+		return string(tv), nil
+	case fmt.Stringer:
+		return tv.String(), nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 64), nil
+	}
+	return "", newConvertErr(v, "string", "")
+}
+
+func toBool(v interface{}) (bool, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.String:
+		switch strings.ToLower(strings.TrimSpace(rv.String())) {
+		case "true", "yes", "on", "1":
+			return true, nil
+		case "false", "no", "off", "0":
+			return false, nil
+		}
+		return false, newConvertErr(v, "bool", "invalid syntax")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() != 0, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint() != 0, nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() != 0, nil
+	}
+	return false, newConvertErr(v, "bool", "")
+}
+
+// bitSizeOf
+//
+// Returns the bit size of the number kind.
+func bitSizeOf(kind reflect.Kind) int {
+	switch kind {
+	case reflect.Int8, reflect.Uint8:
+		return 8
+	case reflect.Int16, reflect.Uint16:
+		return 16
+	case reflect.Int32, reflect.Uint32, reflect.Float32:
+		return 32
+	case reflect.Int, reflect.Uint, reflect.Uintptr:
+		return strconv.IntSize
+	default:
+		return 64
+	}
+}
+
+// toInt
+//
+// Converts v to an integer with the kind (reflect.Int, reflect.Int8, etc.). The result can be converted to the integer type of the kind directly.
+func toInt(v interface{}, kind reflect.Kind, strict bool) (int64, error) {
+	to := kind.String()
+	bitSize := bitSizeOf(kind)
+	var i int64
+	exact := true
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Bool:
+		if rv.Bool() {
+			i = 1
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i = rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := rv.Uint()
+		i = int64(u)
+		exact = u <= math.MaxInt64
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return 0, newConvertErr(v, to, "not a finite number")
+		}
+		i = int64(f)
+		exact = f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64
+	case reflect.String:
+		s := strings.TrimSpace(rv.String())
+		var err error
+		if i, err = strconv.ParseInt(s, 10, 64); err != nil {
+			// maybe a float, like "1.0" or "1e3"
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return 0, newConvertErr(v, to, "invalid syntax")
+			}
+			return toInt(f, kind, strict)
+		}
+	default:
+		return 0, newConvertErr(v, to, "")
+	}
+	if bitSize < 64 && (i < -1<<(bitSize-1) || i > 1<<(bitSize-1)-1) {
+		exact = false
+	}
+	if strict && !exact {
+		return 0, newConvertErr(v, to, "lossy conversion in strict mode")
+	}
+	return i, nil
+}
+
+// toUint
+//
+// Converts v to an unsigned integer with the kind (reflect.Uint, reflect.Uint8, etc.). The result can be converted to the unsigned integer type of the kind directly.
+func toUint(v interface{}, kind reflect.Kind, strict bool) (uint64, error) {
+	to := kind.String()
+	bitSize := bitSizeOf(kind)
+	var u uint64
+	exact := true
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u = rv.Uint()
+	case reflect.String:
+		s := strings.TrimSpace(rv.String())
+		var err error
+		if u, err = strconv.ParseUint(s, 10, 64); err != nil {
+			// maybe a negative integer or a float
+			i, err := toInt(s, reflect.Int64, strict)
+			if err != nil {
+				return 0, newConvertErr(v, to, "invalid syntax")
+			}
+			return toUint(i, kind, strict)
+		}
+	default:
+		i, err := toInt(v, reflect.Int64, strict)
+		if err != nil {
+			if e, ok := err.(convertErr); ok {
+				e.To = to
+				return 0, e
+			}
+			return 0, err
+		}
+		u = uint64(i)
+		exact = i >= 0
+	}
+	if bitSize < 64 && u > 1<<bitSize-1 {
+		exact = false
+	}
+	if strict && !exact {
+		return 0, newConvertErr(v, to, "lossy conversion in strict mode")
+	}
+	return u, nil
+}
+
+// toFloat
+//
+// Converts v to a float with the kind (reflect.Float32 or reflect.Float64). The result can be converted to the float type of the kind directly.
+func toFloat(v interface{}, kind reflect.Kind, strict bool) (float64, error) {
+	to := kind.String()
+	var f float64
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Bool:
+		if rv.Bool() {
+			f = 1
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f = float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		f = float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		f = rv.Float()
+	case reflect.String:
+		var err error
+		if f, err = strconv.ParseFloat(strings.TrimSpace(rv.String()), 64); err != nil {
+			return 0, newConvertErr(v, to, "invalid syntax")
+		}
+	default:
+		return 0, newConvertErr(v, to, "")
+	}
+	if strict && kind == reflect.Float32 && !math.IsInf(f, 0) && math.Abs(f) > math.MaxFloat32 {
+		return 0, newConvertErr(v, to, "lossy conversion in strict mode")
+	}
+	return f, nil
+}
+
+// toRune
+//
+// A string with only one character is converted to the character, and others are converted as int32.
+func toRune(v interface{}, strict bool) (rune, error) {
+	if s, ok := v.(string); ok {
+		if runes := []rune(s); len(runes) == 1 {
+			return runes[0], nil
+		}
+	}
+	i, err := toInt(v, reflect.Int32, strict)
+	if err != nil {
+		return 0, err
+	}
+	return rune(i), nil
+}
+
+// toBytes
+//
+// A []rune is converted to its UTF-8 bytes, and others are converted as string.
+func toBytes(v interface{}) ([]byte, error) {
+	switch tv := v.(type) {
+	case []byte:
+		return tv, nil
+	}
+	s, err := toString(v)
+	if err != nil {
+		return nil, newConvertErr(v, "[]byte", "")
+	}
+	return []byte(s), nil
+}
+
+// toRunes
+//
+// A []byte is converted to the runes of its UTF-8 string, and others are converted as string.
+func toRunes(v interface{}) ([]rune, error) {
+	switch tv := v.(type) {
+	case []rune:
+		return tv, nil
+	}
+	s, err := toString(v)
+	if err != nil {
+		return nil, newConvertErr(v, "[]rune", "")
+	}
+	return []rune(s), nil
+}
+
+// toDuration
+//
+// A string is parsed by time.ParseDuration, and a number (or a numeric string) is regarded as nanoseconds like time.Duration.
+func toDuration(v interface{}, strict bool) (time.Duration, error) {
+	if s, ok := v.(string); ok {
+		if d, err := time.ParseDuration(strings.TrimSpace(s)); err == nil {
+			return d, nil
+		}
+	}
+	i, err := toInt(v, reflect.Int64, strict)
+	if err != nil {
+		return 0, newConvertErr(v, "time.Duration", "invalid duration")
+	}
+	return time.Duration(i), nil
+}
+
+// toTime
+//
+// A string is parsed by the layouts in order, and by time.RFC3339 when no layout is given. A number is regarded as a Unix timestamp in seconds.
+func toTime(v interface{}, layouts []string, strict bool) (time.Time, error) {
+	switch tv := v.(type) {
+	case time.Time:
+		return tv, nil
+	case string:
+		if len(layouts) == 0 {
+			layouts = []string{time.RFC3339}
+		}
+		s := strings.TrimSpace(tv)
+		for _, layout := range layouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, newConvertErr(v, "time.Time", "no layout matched")
+	}
+	i, err := toInt(v, reflect.Int64, strict)
+	if err != nil {
+		return time.Time{}, newConvertErr(v, "time.Time", "")
+	}
+	return time.Unix(i, 0), nil
+}
+
+// toURL
+//
+// A non-empty string is parsed by url.Parse.
+func toURL(v interface{}) (*url.URL, error) {
+	switch tv := v.(type) {
+	case *url.URL:
+		if tv != nil {
+			return tv, nil
+		}
+	case url.URL:
+		return &tv, nil
+	case string:
+		if s := strings.TrimSpace(tv); s != "" {
+			u, err := url.Parse(s)
+			if err != nil {
+				return nil, newConvertErr(v, "*url.URL", err.Error())
+			}
+			return u, nil
+		}
+	}
+	return nil, newConvertErr(v, "*url.URL", "")
+}
+
+// toIP
+//
+// A string is parsed by net.ParseIP.
+func toIP(v interface{}) (net.IP, error) {
+	switch tv := v.(type) {
+	case net.IP:
+		if tv != nil {
+			return tv, nil
+		}
+	case string:
+		if ip := net.ParseIP(strings.TrimSpace(tv)); ip != nil {
+			return ip, nil
+		}
+		return nil, newConvertErr(v, "net.IP", "invalid syntax")
+	}
+	return nil, newConvertErr(v, "net.IP", "")
+}
+
+// byteSizeUnits are the units accepted by toByteSize, the keys are in lower case.
+//
+// The units with `i` (KiB, MiB, etc.) and the single letters (K, M, etc.) are powers of 1024, and the others (KB, MB, etc.) are powers of 1000.
+var byteSizeUnits = map[string]uint64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kb":  1e3,
+	"kib": 1 << 10,
+	"m":   1 << 20,
+	"mb":  1e6,
+	"mib": 1 << 20,
+	"g":   1 << 30,
+	"gb":  1e9,
+	"gib": 1 << 30,
+	"t":   1 << 40,
+	"tb":  1e12,
+	"tib": 1 << 40,
+	"p":   1 << 50,
+	"pb":  1e15,
+	"pib": 1 << 50,
+	"e":   1 << 60,
+	"eb":  1e18,
+	"eib": 1 << 60,
+}
+
+// toByteSize
+//
+// A string like "512MiB", "1.5GB" or "10k" is parsed by the byteSizeUnits, and a number is regarded as bytes.
+func toByteSize(v interface{}, strict bool) (uint64, error) {
+	s, ok := v.(string)
+	if !ok {
+		u, err := toUint(v, reflect.Uint64, strict)
+		if err != nil {
+			return 0, newConvertErr(v, "byte size", "")
+		}
+		return u, nil
+	}
+	s = strings.TrimSpace(s)
+	i := strings.LastIndexAny(s, "0123456789.") + 1
+	num, unit := strings.TrimSpace(s[:i]), strings.ToLower(strings.TrimSpace(s[i:]))
+	scale, ok := byteSizeUnits[unit]
+	if !ok {
+		return 0, newConvertErr(v, "byte size", "unknown unit {"+s[i:]+"}")
+	}
+	if u, err := strconv.ParseUint(num, 10, 64); err == nil {
+		if u > math.MaxUint64/scale {
+			return 0, newConvertErr(v, "byte size", "overflow")
+		}
+		return u * scale, nil
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil || f < 0 {
+		return 0, newConvertErr(v, "byte size", "invalid syntax")
+	}
+	f *= float64(scale)
+	if f >= math.MaxUint64 {
+		return 0, newConvertErr(v, "byte size", "overflow")
+	}
+	if strict && f != math.Trunc(f) {
+		return 0, newConvertErr(v, "byte size", "lossy conversion in strict mode")
+	}
+	return uint64(f), nil
+}
+
+// splitList
+//
+// Splits a comma-separated string to a list, the spaces around each item are trimmed.
+func splitList(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return []string{}
+	}
+	list := strings.Split(s, ",")
+	for i := range list {
+		list[i] = strings.TrimSpace(list[i])
+	}
+	return list
+}
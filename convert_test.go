@@ -0,0 +1,239 @@
+package m2obj
+
+import (
+	"errors"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testLevel int
+
+func TestObject_ValsSemantic(t *testing.T) {
+	// numbers to strings
+	assert.Equal(t, "65", New(65).ValStr())
+	assert.Equal(t, "-3", New(int8(-3)).ValStr())
+	assert.Equal(t, "18446744073709551615", New(uint64(math.MaxUint64)).ValStr())
+	assert.Equal(t, "0.5", New(0.5).ValStr())
+	assert.Equal(t, "1.8", New(float32(1.8)).ValStr())
+	assert.Equal(t, "100000000000000000000", New(1e20).ValStr())
+	assert.Equal(t, "true", New(true).ValStr())
+	assert.Equal(t, "7", New(testLevel(7)).ValStr())
+	assert.Equal(t, "30s", New(30*time.Second).ValStr())
+	assert.Equal(t, []byte("65"), New(65).ValBytes())
+	assert.Equal(t, []rune("65"), New(65).ValRunes())
+	// strings to numbers
+	assert.Equal(t, 42, New("42").ValInt())
+	assert.Equal(t, 42, New(" 42 ").ValInt())
+	assert.Equal(t, int8(-42), New("-42").ValInt8())
+	assert.Equal(t, int64(1000), New("1e3").ValInt64())
+	assert.Equal(t, 1, New("1.8").ValInt())
+	assert.Equal(t, uint64(42), New("42").ValUint())
+	assert.Equal(t, byte(42), New("42").ValByte())
+	assert.Equal(t, 0.5, New("0.5").ValFloat64())
+	assert.Equal(t, float32(0.5), New("0.5").ValFloat32())
+	assert.Equal(t, 'A', New("A").ValRune())
+	assert.Equal(t, 'A', New(65).ValRune())
+	// bools
+	for _, v := range []interface{}{true, "true", "TRUE", "yes", "on", "1", 1, 2.5} {
+		assert.True(t, New(v).ValBool(), "%v", v)
+	}
+	for _, v := range []interface{}{false, "false", "No", "off", "0", 0, 0.0} {
+		assert.False(t, New(v).ValBool(), "%v", v)
+	}
+	assert.Equal(t, 1, New(true).ValInt())
+	assert.Equal(t, 0.0, New(false).ValFloat64())
+	// named types
+	assert.Equal(t, 7, New(testLevel(7)).ValInt())
+	assert.Equal(t, int64(30*time.Second), New(30*time.Second).ValInt64())
+	// float64 from JSON
+	assert.Equal(t, 8080, New(float64(8080)).ValInt())
+
+	// can not be converted
+	for _, f := range []func(){
+		func() { New("abc").ValInt() },
+		func() { New("abc").ValFloat64() },
+		func() { New("abc").ValUint() },
+		func() { New("maybe").ValBool() },
+		func() { New("").ValBool() },
+		func() { New(nil).ValStr() },
+		func() { New(nil).ValInt() },
+		func() { New(Group{}).ValStr() },
+		func() { New(Array{}).ValInt() },
+		func() { New(math.NaN()).ValInt() },
+		func() { New(math.Inf(1)).ValInt64() },
+		func() { New(struct{}{}).ValBytes() },
+	} {
+		func() {
+			defer func() {
+				err, _ := recover().(error)
+				assert.True(t, errors.Is(err, ErrConvert), "%v", err)
+			}()
+			f()
+		}()
+	}
+}
+
+func TestObject_SetStrict(t *testing.T) {
+	obj := New(Group{
+		"float":    1.5,
+		"intFloat": 8080.0,
+		"big":      300,
+		"neg":      -1,
+		"str":      "1.5",
+		"a":        Group{"b": 2.5},
+	})
+	// lossy conversions are allowed by default
+	assert.False(t, obj.IsStrict())
+	assert.Equal(t, 1, obj.MustGet("float").ValInt())
+	assert.Equal(t, int8(44), obj.MustGet("big").ValInt8())
+	assert.Equal(t, uint64(math.MaxUint64), obj.MustGet("neg").ValUint())
+	assert.Equal(t, 1, obj.MustGet("str").ValInt())
+
+	obj.SetStrict(true)
+	assert.True(t, obj.IsStrict())
+	assert.True(t, obj.MustGet("a.b").IsStrict()) // for the whole tree
+	assert.Equal(t, 8080, obj.MustGet("intFloat").ValInt())
+	assert.Equal(t, 300, obj.MustGet("big").ValInt())
+	assert.Equal(t, int16(300), obj.MustGet("big").ValInt16())
+	assert.Equal(t, 1.5, obj.MustGet("float").ValFloat64())
+	for _, keyStr := range []string{"float", "big", "str", "a.b"} {
+		_, err := obj.GetInt8(keyStr)
+		assert.True(t, errors.Is(err, ErrConvert), keyStr)
+		var e convertErr
+		assert.True(t, errors.As(err, &e), keyStr)
+		assert.Equal(t, "int8", e.To, keyStr)
+	}
+	_, err := obj.GetUint("neg")
+	assert.True(t, errors.Is(err, ErrConvert))
+	_, err = obj.GetByte("big")
+	assert.True(t, errors.Is(err, ErrConvert))
+	_, err = obj.GetFloat32("big")
+	assert.NoError(t, err)
+	assert.NoError(t, obj.Set("huge", math.MaxFloat64))
+	_, err = obj.GetFloat32("huge")
+	assert.True(t, errors.Is(err, ErrConvert))
+	assert.Panics(t, func() {
+		obj.MustGet("float").ValInt()
+	})
+	// kept by Clone
+	assert.True(t, obj.Clone().IsStrict())
+	obj.SetStrict(false)
+	assert.Equal(t, 1, obj.MustGet("float").ValInt())
+}
+
+func TestObject_ValsRich(t *testing.T) {
+	obj := New(Group{
+		"timeout":  "30s",
+		"nanos":    1500,
+		"created":  "2021-06-01T08:00:00Z",
+		"day":      "2021-06-01",
+		"unix":     1622534400,
+		"endpoint": "https://example.com:8443/api?x=1",
+		"ip":       "192.168.1.1",
+		"ipv6":     "::1",
+		"sizes": Group{
+			"mib":   "512MiB",
+			"mb":    "1.5 GB",
+			"k":     "10k",
+			"bytes": 4096,
+			"str":   "100",
+		},
+		"hosts":  Array{"a", "b", 3},
+		"ports":  Array{80, "443", 8080.0},
+		"csv":    "a, b ,c",
+		"labels": Group{"env": "prod", "replicas": 3},
+		"bad": Group{
+			"nested": Group{"a": Group{}},
+			"arr":    Array{"x", Array{}},
+		},
+	})
+	assert.Equal(t, 30*time.Second, obj.MustGet("timeout").ValDuration())
+	assert.Equal(t, 1500*time.Nanosecond, obj.MustGet("nanos").ValDuration())
+	assert.Equal(t, time.Date(2021, 6, 1, 8, 0, 0, 0, time.UTC), obj.MustGet("created").ValTime())
+	assert.Equal(t, time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC), obj.MustGet("day").ValTime("2006-01-02"))
+	assert.Equal(t, time.Date(2021, 6, 1, 8, 0, 0, 0, time.UTC), obj.MustGet("created").ValTime("2006-01-02", time.RFC3339))
+	assert.True(t, time.Unix(1622534400, 0).Equal(obj.MustGet("unix").ValTime()))
+	u := obj.MustGet("endpoint").ValURL()
+	assert.Equal(t, "example.com:8443", u.Host)
+	assert.Equal(t, "/api", u.Path)
+	assert.Equal(t, "1", u.Query().Get("x"))
+	assert.Equal(t, "192.168.1.1", obj.MustGet("ip").ValIP().String())
+	assert.Equal(t, "::1", obj.MustGet("ipv6").ValIP().String())
+	assert.Equal(t, uint64(512<<20), obj.MustGet("sizes.mib").ValByteSize())
+	assert.Equal(t, uint64(1.5e9), obj.MustGet("sizes.mb").ValByteSize())
+	assert.Equal(t, uint64(10<<10), obj.MustGet("sizes.k").ValByteSize())
+	assert.Equal(t, uint64(4096), obj.MustGet("sizes.bytes").ValByteSize())
+	assert.Equal(t, uint64(100), obj.MustGet("sizes.str").ValByteSize())
+	assert.Equal(t, []string{"a", "b", "3"}, obj.MustGet("hosts").ValStringSlice())
+	assert.Equal(t, []string{"a", "b", "c"}, obj.MustGet("csv").ValStringSlice())
+	assert.Equal(t, []int{80, 443, 8080}, obj.MustGet("ports").ValIntSlice())
+	assert.Equal(t, []int{80, 443}, New("80,443").ValIntSlice())
+	assert.Equal(t, []string{}, New("").ValStringSlice())
+	assert.Equal(t, map[string]string{"env": "prod", "replicas": "3"}, obj.MustGet("labels").ValStringMap())
+
+	// the same values as Go types
+	now := time.Now()
+	assert.Equal(t, now, New(now).ValTime())
+	assert.Equal(t, time.Minute, New(time.Minute).ValDuration())
+	assert.Equal(t, net.IPv4(1, 2, 3, 4), New(net.IPv4(1, 2, 3, 4)).ValIP())
+
+	// getters
+	d, err := obj.GetDuration("timeout")
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Second, d)
+	assert.Equal(t, time.Minute, obj.GetDurationOr("missing", time.Minute))
+	assert.Equal(t, time.Minute, obj.GetDurationOr("endpoint", time.Minute))
+	day, err := obj.GetTime("day", "2006-01-02")
+	assert.NoError(t, err)
+	assert.Equal(t, 2021, day.Year())
+	assert.Equal(t, now, obj.GetTimeOr("day", now))
+	assert.Equal(t, 1, obj.GetTimeOr("day", now, "2006-01-02").Day())
+	assert.Equal(t, "example.com:8443", obj.GetURLOr("endpoint", nil).Host)
+	assert.Nil(t, obj.GetURLOr("missing", nil))
+	assert.Equal(t, net.IPv6loopback, obj.GetIPOr("endpoint", net.IPv6loopback))
+	assert.Equal(t, uint64(1), obj.GetByteSizeOr("timeout", 1))
+	assert.Equal(t, []string{"x"}, obj.GetStringSliceOr("labels", []string{"x"}))
+	assert.Equal(t, []int{1}, obj.GetIntSliceOr("hosts", []int{1}))
+	assert.Equal(t, map[string]string{}, obj.GetStringMapOr("hosts", map[string]string{}))
+
+	// can not be converted
+	for keyStr, get := range map[string]func(keyStr string) error{
+		"timeout": func(keyStr string) error { _, err := obj.GetTime(keyStr); return err },
+		"day":     func(keyStr string) error { _, err := obj.GetTime(keyStr); return err },
+		"ip":      func(keyStr string) error { _, err := obj.GetDuration(keyStr); return err },
+		"csv":     func(keyStr string) error { _, err := obj.GetIP(keyStr); return err },
+		"labels":  func(keyStr string) error { _, err := obj.GetURL(keyStr); return err },
+		"hosts":   func(keyStr string) error { _, err := obj.GetIntSlice(keyStr); return err },
+		"bad.arr": func(keyStr string) error { _, err := obj.GetStringSlice(keyStr); return err },
+		"nanos":   func(keyStr string) error { _, err := obj.GetStringSlice(keyStr); return err },
+		"ports":   func(keyStr string) error { _, err := obj.GetStringMap(keyStr); return err },
+		"bad.nested": func(keyStr string) error {
+			_, err := obj.GetStringMap(keyStr)
+			return err
+		},
+		"endpoint": func(keyStr string) error { _, err := obj.GetByteSize(keyStr); return err },
+	} {
+		err := get(keyStr)
+		assert.True(t, errors.Is(err, ErrConvert), "%s: %v", keyStr, err)
+	}
+	for _, size := range []string{"", "MiB", "-1", "1.5XB", "99999999999EiB"} {
+		_, err := New(size).GetByteSize("")
+		assert.True(t, errors.Is(err, ErrConvert), size)
+	}
+	_, err = obj.GetIntSlice("hosts")
+	assert.EqualError(t, err, "can not convert value from {*m2obj.arrayData} to {[]int}: the element [0]: can not convert value from {string} to {int}: invalid syntax, at key {hosts} (#0) of keyStr {hosts}")
+
+	// strict mode
+	assert.Equal(t, uint64(1), New("1.5").ValByteSize())
+	obj.SetStrict(true)
+	assert.NoError(t, obj.Set("half", "1.5"))
+	_, err = obj.GetByteSize("half")
+	assert.True(t, errors.Is(err, ErrConvert))
+	assert.NoError(t, obj.Set("ports.[+]", 1.5))
+	_, err = obj.GetIntSlice("ports")
+	assert.True(t, errors.Is(err, ErrConvert))
+}
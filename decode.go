@@ -0,0 +1,352 @@
+package m2obj
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+	urlType             = reflect.TypeOf(url.URL{})
+	ipType              = reflect.TypeOf(net.IP{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	stringerType        = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+)
+
+// Decode
+//
+// Fill the Go value pointed by v with the object. v must be a non-nil pointer, usually to a struct.
+//
+// The value is filled recursively with the same conversion rules of the ValXxx methods:
+//   A struct is filled by a Group, each exported field uses the key named by its `m2obj` tag, or its `json` tag, or its field name.
+//   The tag `-` skips the field, and an embedded struct without a tag is filled by the same Group as its parent.
+//   A map with string keys is filled by a Group, and a slice or an array is filled by an Array (or a comma-separated string).
+//   A nil pointer is allocated before filled.
+//   The keys not found in the object (and the nil objects) leave the Go values as they are, so the defaults can be set before decoding.
+//
+// A *PathError with the keyStr of the failing object will be reported when a value can not be converted.
+//
+// Example:
+//
+//   type ServerConfig struct {
+//     Host    string        `m2obj:"host"`
+//     Port    int           `m2obj:"port"`
+//     Timeout time.Duration `json:"timeout"`
+//   }
+//   var cfg ServerConfig
+//   err := obj.DecodeAt("server", &cfg)
+func (o *Object) Decode(v interface{}) error {
+	defer o.rLock()()
+	return o.decode(Path{}, v)
+}
+
+// DecodeAt
+//
+// Like Decode, but fill v with the child located by the keyStr.
+func (o *Object) DecodeAt(keyStr string, v interface{}) error {
+	p, err := parseKeyStr(keyStr)
+	if err != nil {
+		return err
+	}
+	defer o.rLock()()
+	obj, err := o.get(p)
+	if err != nil {
+		return err
+	}
+	return obj.decode(p, v)
+}
+
+// decode
+//
+// Decode without locking, the object is located by the Path p.
+func (o *Object) decode(p Path, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("the target of Decode must be a non-nil pointer, but got {%T}", v)
+	}
+	d := &decoder{
+		strict: o.isStrict(),
+	}
+	return d.decode(o, p.keyStr, p.Len()-1, lastKey(p), rv.Elem())
+}
+
+// lastKey
+//
+// Returns the last key of the Path, or "" for an empty Path.
+func lastKey(p Path) string {
+	if p.Len() == 0 {
+		return ""
+	}
+	return p.keys[p.Len()-1].key
+}
+
+// fieldKey
+//
+// Returns the key of a struct field by its `m2obj` tag, `json` tag or name. The tagged is false when the key is not from a tag.
+// The key is "-" when the field should be skipped.
+func fieldKey(field reflect.StructField) (key string, tagged bool) {
+	for _, tagName := range []string{"m2obj", "json"} {
+		if tag, ok := field.Tag.Lookup(tagName); ok {
+			if name := strings.Split(tag, ",")[0]; name != "" {
+				return name, true
+			}
+		}
+	}
+	return field.Name, false
+}
+
+// fieldOmitEmpty
+//
+// Returns if the struct field has the `omitempty` option in its `m2obj` tag or `json` tag.
+func fieldOmitEmpty(field reflect.StructField) bool {
+	for _, tagName := range []string{"m2obj", "json"} {
+		for _, opt := range strings.Split(field.Tag.Get(tagName), ",")[1:] {
+			if opt == "omitempty" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decoder
+//
+// The state of a decoding. The keyStr and index of the object being decoded are passed along for the error reports.
+type decoder struct {
+	strict bool
+}
+
+// fail
+//
+// Creates a *PathError for the object at the keyStr which can not be converted to rv.
+func (d *decoder) fail(obj *Object, keyStr string, index int, key string, expected ObjectType, rv reflect.Value, err error) error {
+	if !errors.Is(err, ErrConvert) {
+		err = newConvertErr(obj.valOrNil(), rv.Type().String(), err.Error())
+	}
+	return &PathError{
+		KeyStr:   keyStr,
+		Index:    index,
+		Key:      key,
+		Found:    obj.objType(),
+		Expected: expected,
+		Err:      err,
+	}
+}
+
+// child
+//
+// Returns the keyStr, index and key of a child. The piece is the key escaped in the keyStr.
+func (d *decoder) child(keyStr string, index int, key string, piece string) (string, int, string) {
+	if keyStr == "" {
+		return piece, index + 1, key
+	}
+	return keyStr + "." + piece, index + 1, key
+}
+
+func (d *decoder) decode(obj *Object, keyStr string, index int, key string, rv reflect.Value) error {
+	if obj == nil || obj.val == nil {
+		return nil
+	}
+	// the types with special rules
+	switch rv.Type() {
+	case durationType:
+		dur, err := toDuration(obj.val, d.strict)
+		if err != nil {
+			return d.fail(obj, keyStr, index, key, ValueType, rv, err)
+		}
+		rv.SetInt(int64(dur))
+		return nil
+	case timeType:
+		t, err := toTime(obj.val, nil, d.strict)
+		if err != nil {
+			return d.fail(obj, keyStr, index, key, ValueType, rv, err)
+		}
+		rv.Set(reflect.ValueOf(t))
+		return nil
+	case urlType:
+		u, err := toURL(obj.val)
+		if err != nil {
+			return d.fail(obj, keyStr, index, key, ValueType, rv, err)
+		}
+		rv.Set(reflect.ValueOf(*u))
+		return nil
+	case ipType:
+		ip, err := toIP(obj.val)
+		if err != nil {
+			return d.fail(obj, keyStr, index, key, ValueType, rv, err)
+		}
+		rv.Set(reflect.ValueOf(ip))
+		return nil
+	}
+	if s, ok := obj.val.(string); ok && rv.Kind() != reflect.Ptr && rv.CanAddr() && rv.Addr().Type().Implements(textUnmarshalerType) {
+		if err := rv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return d.fail(obj, keyStr, index, key, ValueType, rv, err)
+		}
+		return nil
+	}
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return d.decode(obj, keyStr, index, key, rv.Elem())
+	case reflect.Interface:
+		if rv.NumMethod() != 0 {
+			return d.fail(obj, keyStr, index, key, ValueType, rv, newConvertErr(obj.val, rv.Type().String(), "unsupported type"))
+		}
+		rv.Set(reflect.ValueOf(obj.staticize()))
+	case reflect.Struct:
+		grp, ok := obj.val.(*groupData)
+		if !ok {
+			return d.fail(obj, keyStr, index, key, GroupType, rv, newConvertErr(obj.val, rv.Type().String(), ""))
+		}
+		return d.decodeStruct(*grp, keyStr, index, rv)
+	case reflect.Map:
+		grp, ok := obj.val.(*groupData)
+		if !ok || rv.Type().Key().Kind() != reflect.String {
+			return d.fail(obj, keyStr, index, key, GroupType, rv, newConvertErr(obj.val, rv.Type().String(), ""))
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMapWithSize(rv.Type(), len(*grp)))
+		}
+		for k, child := range *grp {
+			elem := reflect.New(rv.Type().Elem()).Elem()
+			childKeyStr, childIndex, childKey := d.child(keyStr, index, k, escapeKey(k))
+			if err := d.decode(child, childKeyStr, childIndex, childKey, elem); err != nil {
+				return err
+			}
+			rv.SetMapIndex(reflect.ValueOf(k).Convert(rv.Type().Key()), elem)
+		}
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 && !obj.isArray() { // []byte
+			bytes, err := toBytes(obj.val)
+			if err != nil {
+				return d.fail(obj, keyStr, index, key, ValueType, rv, err)
+			}
+			if rv.Kind() == reflect.Slice {
+				rv.SetBytes(append([]byte{}, bytes...))
+			} else {
+				reflect.Copy(rv, reflect.ValueOf(bytes))
+			}
+			return nil
+		}
+		vals, err := obj.listVals(rv.Type().String())
+		if err != nil {
+			return d.fail(obj, keyStr, index, key, ArrayType, rv, err)
+		}
+		if rv.Kind() == reflect.Slice {
+			rv.Set(reflect.MakeSlice(rv.Type(), len(vals), len(vals)))
+		}
+		for i := 0; i < len(vals) && i < rv.Len(); i++ {
+			var child *Object
+			if arr, ok := obj.val.(*arrayData); ok {
+				child = (*arr)[i]
+			} else { // an item of a comma-separated string
+				child = New(vals[i])
+			}
+			piece := "[" + strconv.Itoa(i) + "]"
+			childKeyStr, childIndex, childKey := d.child(keyStr, index, piece, piece)
+			if err := d.decode(child, childKeyStr, childIndex, childKey, rv.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.String:
+		s, err := toString(obj.val)
+		if err != nil {
+			return d.fail(obj, keyStr, index, key, ValueType, rv, err)
+		}
+		rv.SetString(s)
+	case reflect.Bool:
+		b, err := toBool(obj.val)
+		if err != nil {
+			return d.fail(obj, keyStr, index, key, ValueType, rv, err)
+		}
+		rv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := toInt(obj.val, rv.Kind(), d.strict)
+		if err != nil {
+			return d.fail(obj, keyStr, index, key, ValueType, rv, err)
+		}
+		rv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := toUint(obj.val, rv.Kind(), d.strict)
+		if err != nil {
+			return d.fail(obj, keyStr, index, key, ValueType, rv, err)
+		}
+		rv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := toFloat(obj.val, rv.Kind(), d.strict)
+		if err != nil {
+			return d.fail(obj, keyStr, index, key, ValueType, rv, err)
+		}
+		rv.SetFloat(f)
+	default:
+		return d.fail(obj, keyStr, index, key, ValueType, rv, newConvertErr(obj.val, rv.Type().String(), "unsupported type"))
+	}
+	return nil
+}
+
+// decodeStruct
+//
+// Fills the fields of the struct rv by the Group grp.
+func (d *decoder) decodeStruct(grp groupData, keyStr string, index int, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		name, tagged := fieldKey(field)
+		if name == "-" {
+			continue
+		}
+		fv := rv.Field(i)
+		if field.Anonymous && !tagged { // embedded struct shares the Group
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if fv.Kind() == reflect.Ptr {
+					if !fv.CanSet() {
+						continue
+					}
+					if fv.IsNil() {
+						fv.Set(reflect.New(ft))
+					}
+					fv = fv.Elem()
+				}
+				if err := d.decodeStruct(grp, keyStr, index, fv); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if field.PkgPath != "" { // unexported
+			continue
+		}
+		child, ok := grp[name]
+		if !ok { // case-insensitive matching, like encoding/json
+			for k, v := range grp {
+				if strings.EqualFold(k, name) {
+					name, child, ok = k, v, true
+					break
+				}
+			}
+		}
+		if !ok {
+			continue
+		}
+		childKeyStr, childIndex, childKey := d.child(keyStr, index, name, escapeKey(name))
+		if err := d.decode(child, childKeyStr, childIndex, childKey, fv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
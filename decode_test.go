@@ -0,0 +1,217 @@
+package m2obj
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testUpper string
+
+func (u *testUpper) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		return errors.New("empty text")
+	}
+	*u = testUpper(strings.ToUpper(string(text)))
+	return nil
+}
+
+type testBase struct {
+	Name    string `m2obj:"name"`
+	Version int
+}
+
+type testServerConfig struct {
+	Host    string        `m2obj:"host"`
+	Port    int           `json:"port,omitempty"`
+	Timeout time.Duration `m2obj:"timeout" json:"ignored"`
+	Debug   bool
+	Ignored string `m2obj:"-"`
+	private string
+}
+
+type testConfig struct {
+	testBase
+	Server   testServerConfig            `m2obj:"server"`
+	Backup   *testServerConfig           `m2obj:"backup"`
+	Tags     []string                    `m2obj:"tags"`
+	Ports    []int                       `m2obj:"ports"`
+	Weights  [2]float64                  `m2obj:"weights"`
+	Labels   map[string]string           `m2obj:"labels"`
+	Servers  map[string]testServerConfig `m2obj:"servers"`
+	Extra    interface{}                 `m2obj:"extra"`
+	IP       net.IP                      `m2obj:"ip"`
+	Created  time.Time                   `m2obj:"created"`
+	Level    testUpper                   `m2obj:"level"`
+	Data     []byte                      `m2obj:"data"`
+	Default  string                      `m2obj:"default"`
+	Optional *int                        `m2obj:"optional"`
+}
+
+func TestObject_Decode(t *testing.T) {
+	obj := New(Group{
+		"name":    "app",
+		"version": "3",
+		"server": Group{
+			"host":    "localhost",
+			"port":    "8080",
+			"timeout": "30s",
+			"debug":   "yes",
+			"Ignored": "x",
+			"private": "x",
+		},
+		"backup": Group{
+			"host": "backup.local",
+			"port": 9090.0,
+		},
+		"tags":    "a, b",
+		"ports":   Array{80, "443"},
+		"weights": Array{0.5, 1, 2},
+		"labels": Group{
+			"env":      "prod",
+			"replicas": 3,
+		},
+		"servers": Group{
+			"a.b": Group{
+				"host": "ab",
+			},
+		},
+		"extra": Group{
+			"list": Array{1, "2"},
+		},
+		"ip":       "10.0.0.1",
+		"created":  "2021-06-01T08:00:00Z",
+		"level":    "debug",
+		"data":     "bytes",
+		"optional": nil,
+	})
+	cfg := testConfig{
+		Default: "default",
+		Server: testServerConfig{
+			Ignored: "kept",
+		},
+	}
+	assert.NoError(t, obj.Decode(&cfg))
+	assert.Equal(t, testConfig{
+		testBase: testBase{
+			Name:    "app",
+			Version: 3,
+		},
+		Server: testServerConfig{
+			Host:    "localhost",
+			Port:    8080,
+			Timeout: 30 * time.Second,
+			Debug:   true,
+			Ignored: "kept",
+		},
+		Backup: &testServerConfig{
+			Host: "backup.local",
+			Port: 9090,
+		},
+		Tags:    []string{"a", "b"},
+		Ports:   []int{80, 443},
+		Weights: [2]float64{0.5, 1},
+		Labels: map[string]string{
+			"env":      "prod",
+			"replicas": "3",
+		},
+		Servers: map[string]testServerConfig{
+			"a.b": {Host: "ab"},
+		},
+		Extra: map[string]interface{}{
+			"list": []interface{}{1, "2"},
+		},
+		IP:      net.ParseIP("10.0.0.1"),
+		Created: time.Date(2021, 6, 1, 8, 0, 0, 0, time.UTC),
+		Level:   "DEBUG",
+		Data:    []byte("bytes"),
+		Default: "default",
+	}, cfg)
+
+	// DecodeAt
+	var server testServerConfig
+	assert.NoError(t, obj.DecodeAt("server", &server))
+	assert.Equal(t, "localhost", server.Host)
+	var port int
+	assert.NoError(t, obj.DecodeAt("server.port", &port))
+	assert.Equal(t, 8080, port)
+	var ports []uint16
+	assert.NoError(t, obj.DecodeAt("ports", &ports))
+	assert.Equal(t, []uint16{80, 443}, ports)
+	var m map[string]interface{}
+	assert.NoError(t, obj.DecodeAt("backup", &m))
+	assert.Equal(t, map[string]interface{}{"host": "backup.local", "port": 9090.0}, m)
+	assert.True(t, errors.Is(obj.DecodeAt("missing", &server), ErrKeyNotFound))
+	assert.True(t, errors.Is(obj.DecodeAt(`"`, &server), ErrInvalidKeyStr))
+
+	// invalid targets
+	assert.Error(t, obj.Decode(cfg))
+	assert.Error(t, obj.Decode(nil))
+	assert.Error(t, obj.Decode((*testConfig)(nil)))
+}
+
+func TestObject_DecodeError(t *testing.T) {
+	obj := New(Group{
+		"server": Group{
+			"port":    "http",
+			"timeout": "1.5",
+		},
+		"servers": Group{
+			"example.com": Group{
+				"port": Array{},
+			},
+		},
+		"ports":  Array{80, "x"},
+		"level":  "",
+		"labels": Array{},
+	})
+	type TestData struct {
+		keyStr   string
+		target   interface{}
+		want     PathError
+		wantFrom string
+		wantTo   string
+	}
+	testData := []TestData{
+		{"server", &testServerConfig{}, PathError{KeyStr: "server.port", Index: 1, Key: "port", Found: ValueType, Expected: ValueType}, "string", "int"},
+		{"", &struct {
+			Servers map[string]testServerConfig
+		}{}, PathError{KeyStr: `servers.example\.com.port`, Index: 2, Key: "port", Found: ArrayType, Expected: ValueType}, "*m2obj.arrayData", "int"},
+		{"", &struct {
+			Ports []int `m2obj:"ports"`
+		}{}, PathError{KeyStr: "ports.[1]", Index: 1, Key: "[1]", Found: ValueType, Expected: ValueType}, "string", "int"},
+		{"", &struct {
+			Labels map[string]string
+		}{}, PathError{KeyStr: "labels", Index: 0, Key: "labels", Found: ArrayType, Expected: GroupType}, "*m2obj.arrayData", "map[string]string"},
+		{"", &struct {
+			Level testUpper
+		}{}, PathError{KeyStr: "level", Index: 0, Key: "level", Found: ValueType, Expected: ValueType}, "string", "m2obj.testUpper"},
+		{"server.port", new(bool), PathError{KeyStr: "server.port", Index: 1, Key: "port", Found: ValueType, Expected: ValueType}, "string", "bool"},
+		{"server", new([]int), PathError{KeyStr: "server", Index: 0, Key: "server", Found: GroupType, Expected: ArrayType}, "*m2obj.groupData", "[]int"},
+		{"", new(int), PathError{KeyStr: "", Index: -1, Found: GroupType, Expected: ValueType}, "*m2obj.groupData", "int"},
+	}
+	for _, data := range testData {
+		err := obj.DecodeAt(data.keyStr, data.target)
+		assert.True(t, errors.Is(err, ErrConvert), "%s: %v", data.want.KeyStr, err)
+		var pathErr *PathError
+		if assert.True(t, errors.As(err, &pathErr), data.want.KeyStr) {
+			var e convertErr
+			assert.True(t, errors.As(pathErr.Err, &e))
+			assert.Equal(t, data.wantFrom, e.From, data.want.KeyStr)
+			assert.Equal(t, data.wantTo, e.To, data.want.KeyStr)
+			pathErr.Err = nil
+			assert.Equal(t, data.want, *pathErr)
+		}
+	}
+
+	// strict mode
+	var timeout time.Duration
+	assert.NoError(t, obj.DecodeAt("server.timeout", &timeout))
+	assert.Equal(t, time.Duration(1), timeout)
+	obj.SetStrict(true)
+	assert.True(t, errors.Is(obj.DecodeAt("server.timeout", &timeout), ErrConvert))
+}
@@ -0,0 +1,333 @@
+package m2obj
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DiffOp
+//
+// The kind of a Change reported by Diff.
+type DiffOp int
+
+const (
+	DiffAdded    DiffOp = iota + 1 // the key or element only exists in b
+	DiffRemoved                    // the key or element only exists in a
+	DiffModified                   // the values in a and b are different
+	DiffMoved                      // the element is moved in the Array, only reported with DiffLCS
+)
+
+func (op DiffOp) String() string {
+	switch op {
+	case DiffAdded:
+		return "added"
+	case DiffRemoved:
+		return "removed"
+	case DiffModified:
+		return "modified"
+	case DiffMoved:
+		return "moved"
+	default:
+		return "unknown"
+	}
+}
+
+// Change
+//
+// A difference between two objects, see Diff.
+type Change struct {
+	// the keyStr of the changed object, which locates it in b, or in a when it is removed
+	KeyStr string
+	// the keyStr in a of a moved element, only set for DiffMoved
+	From string
+	// the kind of the change
+	Op DiffOp
+	// the staticized values (see Staticize) in a and b, nil when the object doesn't exist
+	Old interface{}
+	New interface{}
+}
+
+// String
+//
+// Renders the change as a hunk of a unified diff, such as:
+//
+//   @@ db.pool.size @@
+//   -10
+//   +20
+func (c Change) String() string {
+	var buf strings.Builder
+	switch c.Op {
+	case DiffMoved:
+		buf.WriteString("@@ " + c.From + " -> " + c.KeyStr + " @@\n")
+		buf.WriteString(" " + formatDiffValue(c.New) + "\n")
+	default:
+		buf.WriteString("@@ " + c.KeyStr + " @@\n")
+		if c.Op != DiffAdded {
+			buf.WriteString("-" + formatDiffValue(c.Old) + "\n")
+		}
+		if c.Op != DiffRemoved {
+			buf.WriteString("+" + formatDiffValue(c.New) + "\n")
+		}
+	}
+	return buf.String()
+}
+
+// FormatDiff
+//
+// Renders the changes reported by Diff as a text similar to a unified diff, the values are rendered as JSON.
+//
+// Example:
+//
+//   fmt.Print(m2obj.FormatDiff(m2obj.Diff(before, after)))
+//   // --- a
+//   // +++ b
+//   // @@ db.pool.size @@
+//   // -10
+//   // +20
+//   // @@ servers.[2] @@
+//   // +"s2"
+func FormatDiff(changes []Change) string {
+	if len(changes) == 0 {
+		return ""
+	}
+	var buf strings.Builder
+	buf.WriteString("--- a\n+++ b\n")
+	for _, c := range changes {
+		buf.WriteString(c.String())
+	}
+	return buf.String()
+}
+
+func formatDiffValue(v interface{}) string {
+	if buf, err := json.Marshal(v); err == nil {
+		return string(buf)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// DiffOption
+//
+// The option of Diff, such as DiffLCS.
+type DiffOption func(d *differ)
+
+// DiffLCS
+//
+// Compares Arrays by the longest common subsequence instead of by index,
+// so that the inserted, removed and moved elements are detected instead of reporting all the elements behind them as modified.
+func DiffLCS() DiffOption {
+	return func(d *differ) {
+		d.lcs = true
+	}
+}
+
+// Diff
+//
+// Compares two objects recursively, reports the added, removed and modified keys of Groups and elements of Arrays.
+// The keys of a Group are reported in sorted order, and the Arrays are compared by index unless DiffLCS is given.
+//
+// Returns nil when there is no difference. A nil object is regarded as a nil value.
+//
+// Example:
+//
+//   for _, c := range m2obj.Diff(before, after) {
+//     fmt.Println(c.Op, c.KeyStr, c.Old, c.New) // modified db.pool.size 10 20
+//   }
+func Diff(a, b *Object, opts ...DiffOption) []Change {
+	d := &differ{}
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.diff(nil, lockedSnapshot(a), lockedSnapshot(b))
+	return d.changes
+}
+
+// lockedSnapshot
+//
+// Like snapshot, but locks the tree for reading.
+func lockedSnapshot(o *Object) interface{} {
+	if o == nil {
+		return nil
+	}
+	defer o.rLock()()
+	return o.snapshot()
+}
+
+// differ
+//
+// The state of a diffing, works on the staticized values.
+type differ struct {
+	lcs     bool
+	changes []Change
+}
+
+func (d *differ) add(keys []keyItem, op DiffOp, old, new interface{}) {
+	d.changes = append(d.changes, Change{
+		KeyStr: joinKeyItems(keys),
+		Op:     op,
+		Old:    old,
+		New:    new,
+	})
+}
+
+// child
+//
+// Returns the keys of a child, the keys of the parent are never changed.
+func (d *differ) child(keys []keyItem, item keyItem) []keyItem {
+	return append(keys[:len(keys):len(keys)], item)
+}
+
+func (d *differ) diff(keys []keyItem, a, b interface{}) {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		if bv, ok := b.(map[string]interface{}); ok {
+			d.diffGroup(keys, av, bv)
+			return
+		}
+	case []interface{}:
+		if bv, ok := b.([]interface{}); ok {
+			if d.lcs {
+				d.diffArrayLCS(keys, av, bv)
+			} else {
+				d.diffArray(keys, av, bv)
+			}
+			return
+		}
+	}
+	if !reflect.DeepEqual(a, b) {
+		d.add(keys, DiffModified, a, b)
+	}
+}
+
+func (d *differ) diffGroup(keys []keyItem, a, b map[string]interface{}) {
+	allKeys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		allKeys = append(allKeys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			allKeys = append(allKeys, k)
+		}
+	}
+	sort.Strings(allKeys)
+	for _, k := range allKeys {
+		av, inA := a[k]
+		bv, inB := b[k]
+		childKeys := d.child(keys, keyItem{key: k})
+		switch {
+		case !inB:
+			d.add(childKeys, DiffRemoved, av, nil)
+		case !inA:
+			d.add(childKeys, DiffAdded, nil, bv)
+		default:
+			d.diff(childKeys, av, bv)
+		}
+	}
+}
+
+func (d *differ) diffArray(keys []keyItem, a, b []interface{}) {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		d.diff(d.child(keys, indexKeyItem(i)), a[i], b[i])
+	}
+	for i := len(a); i < len(b); i++ {
+		d.add(d.child(keys, indexKeyItem(i)), DiffAdded, nil, b[i])
+	}
+	for i := len(a) - 1; i >= len(b); i-- { // from the end, so that they can be removed one by one
+		d.add(d.child(keys, indexKeyItem(i)), DiffRemoved, a[i], nil)
+	}
+}
+
+// diffArrayLCS
+//
+// Aligns the Arrays by the longest common subsequence of equal elements. Of the unaligned elements,
+// a removed one equal to an added one is reported as moved, then the rest between two aligned elements are paired as modified,
+// and the unpaired ones are reported as removed or added.
+func (d *differ) diffArrayLCS(keys []keyItem, a, b []interface{}) {
+	// lengths[i][j] is the length of the LCS of a[i:] and b[j:]
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if reflect.DeepEqual(a[i], b[j]) {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+	// the runs of unaligned elements, which are between two aligned ones
+	type run struct {
+		ra, rb []int
+	}
+	runs := []run{{}}
+	for i, j := 0, 0; i < len(a) || j < len(b); {
+		last := &runs[len(runs)-1]
+		switch {
+		case i < len(a) && j < len(b) && reflect.DeepEqual(a[i], b[j]):
+			runs = append(runs, run{})
+			i++
+			j++
+		case j == len(b) || i < len(a) && lengths[i+1][j] >= lengths[i][j+1]:
+			last.ra = append(last.ra, i)
+			i++
+		default:
+			last.rb = append(last.rb, j)
+			j++
+		}
+	}
+	// moved
+	var moved []Change
+	movedA, movedB := make(map[int]bool), make(map[int]bool)
+	for _, r := range runs {
+		for _, i := range r.ra {
+			for _, r2 := range runs {
+				for _, j := range r2.rb {
+					if !movedA[i] && !movedB[j] && reflect.DeepEqual(a[i], b[j]) {
+						movedA[i], movedB[j] = true, true
+						moved = append(moved, Change{
+							KeyStr: joinKeyItems(d.child(keys, indexKeyItem(j))),
+							From:   joinKeyItems(d.child(keys, indexKeyItem(i))),
+							Op:     DiffMoved,
+							Old:    a[i],
+							New:    b[j],
+						})
+					}
+				}
+			}
+		}
+	}
+	// modified
+	var removed, added []int
+	for _, r := range runs {
+		var ra, rb []int
+		for _, i := range r.ra {
+			if !movedA[i] {
+				ra = append(ra, i)
+			}
+		}
+		for _, j := range r.rb {
+			if !movedB[j] {
+				rb = append(rb, j)
+			}
+		}
+		for len(ra) > 0 && len(rb) > 0 {
+			d.diff(d.child(keys, indexKeyItem(rb[0])), a[ra[0]], b[rb[0]])
+			ra, rb = ra[1:], rb[1:]
+		}
+		removed = append(removed, ra...)
+		added = append(added, rb...)
+	}
+	d.changes = append(d.changes, moved...)
+	for _, i := range removed {
+		d.add(d.child(keys, indexKeyItem(i)), DiffRemoved, a[i], nil)
+	}
+	for _, j := range added {
+		d.add(d.child(keys, indexKeyItem(j)), DiffAdded, nil, b[j])
+	}
+}
@@ -0,0 +1,102 @@
+package m2obj
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	a := New(Group{
+		"db": Group{
+			"pool": Group{
+				"size": 10,
+			},
+			"name": "main",
+		},
+		"servers": Array{"s0", "s1"},
+		"removed": true,
+		"type":    Array{1},
+	})
+	b := New(Group{
+		"db": Group{
+			"pool": Group{
+				"size": 20,
+			},
+			"name": "main",
+		},
+		"servers": Array{"s0", "s1.5", "s2"},
+		"added":   "yes",
+		"type":    Group{},
+	})
+	changes := Diff(a, b)
+	assert.Equal(t, []Change{
+		{KeyStr: "added", Op: DiffAdded, New: "yes"},
+		{KeyStr: "db.pool.size", Op: DiffModified, Old: 10, New: 20},
+		{KeyStr: "removed", Op: DiffRemoved, Old: true},
+		{KeyStr: "servers.[1]", Op: DiffModified, Old: "s1", New: "s1.5"},
+		{KeyStr: "servers.[2]", Op: DiffAdded, New: "s2"},
+		{KeyStr: "type", Op: DiffModified, Old: []interface{}{1}, New: map[string]interface{}{}},
+	}, changes)
+	assert.Equal(t, `--- a
++++ b
+@@ added @@
++"yes"
+@@ db.pool.size @@
+-10
++20
+@@ removed @@
+-true
+@@ servers.[1] @@
+-"s1"
++"s1.5"
+@@ servers.[2] @@
++"s2"
+@@ type @@
+-[1]
++{}
+`, FormatDiff(changes))
+
+	// no difference
+	assert.Nil(t, Diff(a, a.Clone()))
+	assert.Equal(t, "", FormatDiff(nil))
+	assert.Nil(t, Diff(nil, New(nil)))
+	assert.Equal(t, []Change{
+		{KeyStr: "", Op: DiffModified, Old: nil, New: 1},
+	}, Diff(nil, New(1)))
+	// escaped keys
+	assert.Equal(t, []Change{
+		{KeyStr: `hosts.example\.com`, Op: DiffAdded, New: 80},
+	}, Diff(New(Group{"hosts": Group{}}), New(Group{"hosts": Group{"example.com": 80}})))
+}
+
+func TestDiffLCS(t *testing.T) {
+	a := New(Array{"a", "b", "c", Group{"name": "d", "port": 1}, "e"})
+	b := New(Array{"x", "c", "a", "b", Group{"name": "d", "port": 2}})
+	// by index
+	assert.Equal(t, 5, len(Diff(a, b)))
+	// by LCS
+	changes := Diff(a, b, DiffLCS())
+	assert.Equal(t, []Change{
+		{KeyStr: "[4].port", Op: DiffModified, Old: 1, New: 2},
+		{KeyStr: "[1]", From: "[2]", Op: DiffMoved, Old: "c", New: "c"},
+		{KeyStr: "[4]", Op: DiffRemoved, Old: "e"},
+		{KeyStr: "[0]", Op: DiffAdded, New: "x"},
+	}, changes)
+	assert.Equal(t, "@@ [2] -> [1] @@\n \"c\"\n", changes[1].String())
+	// inserted and removed
+	assert.Equal(t, []Change{
+		{KeyStr: "[1]", Op: DiffAdded, New: 1.5},
+	}, Diff(New(Array{1, 2}), New(Array{1, 1.5, 2}), DiffLCS()))
+	assert.Equal(t, []Change{
+		{KeyStr: "[0]", Op: DiffRemoved, Old: 1},
+	}, Diff(New(Array{1, 2}), New(Array{2}), DiffLCS()))
+}
+
+func TestDiffOp_String(t *testing.T) {
+	assert.Equal(t, "added", DiffAdded.String())
+	assert.Equal(t, "removed", DiffRemoved.String())
+	assert.Equal(t, "modified", DiffModified.String())
+	assert.Equal(t, "moved", DiffMoved.String())
+	assert.Equal(t, "unknown", DiffOp(0).String())
+}
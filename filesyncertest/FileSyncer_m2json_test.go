@@ -1,12 +1,21 @@
 package filesyncertest
 
 import (
+	"context"
+	"errors"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/rickonono3/m2obj"
 	"github.com/rickonono3/m2obj/m2json"
+	"github.com/rickonono3/m2obj/m2schema"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -19,7 +28,7 @@ func TestFileSyncer_m2json_Save(t *testing.T) {
 	assert.Error(t, fs.Save())
 	fs.BindObject(obj)
 	assert.NoError(t, fs.Save())
-	fileBytes, err := ioutil.ReadFile(filePath)
+	fileBytes, err := ioutil.ReadFile(homeFilePath)
 	assert.NoError(t, err)
 	fileObj, err := formatter.Unmarshal(fileBytes)
 	assert.NoError(t, err)
@@ -43,35 +52,37 @@ func TestFileSyncer_m2json_AutoSave(t *testing.T) {
 	initTestData("json")
 	formatter := m2json.Formatter{}
 	fs := m2obj.NewFileSyncer(filePath, formatter)
+	assert.NoError(t, fs.Start(context.Background()))
+	defer fs.Close()
 	checkObj := func(t *testing.T) {
-		fileBytes, err := ioutil.ReadFile(filePath)
+		fileBytes, err := ioutil.ReadFile(homeFilePath)
 		assert.NoError(t, err)
 		fileObj, err := formatter.Unmarshal(fileBytes)
 		assert.NoError(t, err)
 		assert.Equal(t, allNumbersToFloat64(obj.Staticize()), allNumbersToFloat64(fileObj.Staticize()))
 	}
 	// init save
-	fs.AutoSaveTiming = 500
+	fs.SetAutoSaveTiming(500)
 	time.Sleep(waitStartTime)
 	fs.BindObject(obj)
 	time.Sleep(waitStartTime)
-	fs.AutoSaveTiming = -1
+	fs.SetAutoSaveTiming(-1)
 	time.Sleep(waitStopTime)
 	t.Run("init save", checkObj)
 	// first save
 	obj.Remove("e.ea")
-	fs.AutoSaveTiming = 500
+	fs.SetAutoSaveTiming(500)
 	time.Sleep(waitStartTime)
-	fs.AutoSaveTiming = -1
+	fs.SetAutoSaveTiming(-1)
 	time.Sleep(waitStopTime)
 	t.Run("first save", checkObj)
 	// second save
 	obj.SetVal(m2obj.Group{
 		"secondView": true,
 	})
-	fs.AutoSaveTiming = 500
+	fs.SetAutoSaveTiming(500)
 	time.Sleep(waitStartTime)
-	fs.AutoSaveTiming = -1
+	fs.SetAutoSaveTiming(-1)
 	time.Sleep(waitStopTime)
 	t.Run("second save", checkObj)
 }
@@ -82,7 +93,9 @@ func TestFileSyncer_m2json_AutoLoad(t *testing.T) {
 	initTestData("json")
 	formatter := m2json.Formatter{}
 	fs := m2obj.NewFileSyncer(filePath, formatter)
-	fs.AutoSaveTiming = -1
+	assert.NoError(t, fs.Start(context.Background()))
+	defer fs.Close()
+	fs.SetAutoSaveTiming(-1)
 	obj2 := obj.Clone()
 	checkObjEqual := func(t *testing.T) {
 		assert.Equal(t, allNumbersToFloat64(obj.Staticize()), allNumbersToFloat64(obj2.Staticize()))
@@ -99,9 +112,9 @@ func TestFileSyncer_m2json_AutoLoad(t *testing.T) {
 	obj.Remove("d")
 	obj.Remove("e")
 	t.Run("first load before", checkObjNotEqual)
-	fs.AutoLoadTiming = 500
+	fs.SetAutoLoadTiming(500)
 	time.Sleep(waitStartTime)
-	fs.AutoLoadTiming = -1
+	fs.SetAutoLoadTiming(-1)
 	time.Sleep(waitStopTime)
 	t.Run("first load after", checkObjEqual)
 	// second load
@@ -111,7 +124,7 @@ func TestFileSyncer_m2json_AutoLoad(t *testing.T) {
 	assert.NoError(t, fs.Save())
 	obj.SetVal(obj2.Clone())
 	t.Run("second load before", checkObjEqual)
-	fs.AutoLoadTiming = 500
+	fs.SetAutoLoadTiming(500)
 	time.Sleep(waitStartTime)
 	t.Run("second load after", checkObjNotEqual)
 	t.Run("second load after content check", func(t *testing.T) {
@@ -122,7 +135,7 @@ func TestFileSyncer_m2json_AutoLoad(t *testing.T) {
 		assert.Equal(t, allNumbersToFloat64(expect.Staticize()), allNumbersToFloat64(obj.Staticize()))
 	})
 	// HardLoad
-	fs.HardLoad = true
+	fs.SetHardLoad(true)
 	time.Sleep(waitStartTime)
 	t.Run("second load after content check with HardLoad", func(t *testing.T) {
 		expect := m2obj.New(m2obj.Group{
@@ -174,7 +187,7 @@ func TestFileSyncer_m2json_AutoLoadOnChange(t *testing.T) {
 	formatter := m2json.Formatter{}
 	fs := m2obj.NewFileSyncer(filePath, formatter)
 	getActualFileObj := func() (fileObj *m2obj.Object) {
-		fileBytes, err := ioutil.ReadFile(filePath)
+		fileBytes, err := ioutil.ReadFile(homeFilePath)
 		assert.NoError(t, err)
 		fileObj, err = formatter.Unmarshal(fileBytes)
 		assert.NoError(t, err)
@@ -206,3 +219,447 @@ func TestFileSyncer_m2json_AutoLoadOnChange(t *testing.T) {
 	assert.True(t, obj.Remove("t"))
 	checkObjsEqual()
 }
+
+func TestFileSyncer_m2json_Concurrent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "m2obj")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.json")
+	formatter := m2json.Formatter{}
+	fs := m2obj.NewFileSyncer(path, formatter)
+	cObj := m2obj.New(m2obj.Group{})
+	fs.BindObject(cObj)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				assert.NoError(t, cObj.Set("k"+strconv.Itoa(i), float64(j)))
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				assert.NoError(t, fs.Save())
+			}
+		}()
+	}
+	wg.Wait()
+	assert.NoError(t, fs.Save())
+	fileBytes, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	fileObj, err := formatter.Unmarshal(fileBytes)
+	assert.NoError(t, err)
+	assert.Equal(t, cObj.Staticize(), fileObj.Staticize())
+}
+
+func TestFileSyncer_m2json_LoadWithOtherWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "m2obj")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.json")
+	formatter := m2json.Formatter{}
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"a": 1}`), 0644))
+	fs := m2obj.NewFileSyncer(path, formatter)
+	wObj := m2obj.New(m2obj.Group{})
+	fs.BindObject(wObj)
+	// another writer changes the object while the loading is still running
+	var once sync.Once
+	_, err = wObj.Watch("a", func(ev m2obj.ChangeEvent) {
+		once.Do(func() {
+			assert.NoError(t, wObj.Set("b", float64(2)))
+		})
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, fs.Load())
+	fileBytes, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	fileObj, err := formatter.Unmarshal(fileBytes)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"a": float64(1), "b": float64(2)}, fileObj.Staticize())
+}
+
+func TestFileSyncer_m2json_Lifecycle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "m2obj")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.json")
+	formatter := m2json.Formatter{}
+	goroutines := runtime.NumGoroutine()
+
+	// flush on Close
+	fs := m2obj.NewFileSyncer(path, formatter, m2obj.WithAutoSaveTiming(time.Hour.Milliseconds()))
+	assert.Equal(t, time.Hour.Milliseconds(), fs.GetAutoSaveTiming())
+	lObj := m2obj.New(m2obj.Group{})
+	fs.BindObject(lObj)
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.NoError(t, fs.Start(ctx))
+	assert.True(t, errors.Is(fs.Start(ctx), m2obj.ErrSyncerStarted))
+	assert.NoError(t, lObj.Set("a", float64(1)))
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+	assert.NoError(t, fs.Close())
+	assert.NoError(t, fs.Close())
+	assert.True(t, errors.Is(fs.Start(ctx), m2obj.ErrSyncerClosed))
+	fileBytes, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	fileObj, err := formatter.Unmarshal(fileBytes)
+	assert.NoError(t, err)
+	assert.Equal(t, lObj.Staticize(), fileObj.Staticize())
+	// unbound after Close
+	assert.NoError(t, lObj.Set("a", float64(2)))
+	fileBytes, err = ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(fileBytes), "2")
+
+	// stopped by the context
+	fs = m2obj.NewFileSyncer(path, formatter, m2obj.WithAutoLoadTiming(10), m2obj.WithHardLoad(true))
+	assert.True(t, fs.GetHardLoad())
+	fs.BindObject(m2obj.New(m2obj.Group{}))
+	assert.NoError(t, fs.Start(ctx))
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, float64(1), fs.GetBoundObject().MustGet("a").ValFloat64())
+	cancel()
+
+	// no goroutine is leaked
+	for i := 0; i < 100 && runtime.NumGoroutine() > goroutines; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, goroutines, runtime.NumGoroutine())
+	assert.NoError(t, fs.Close())
+}
+
+func TestFileSyncer_m2json_AtomicSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "m2obj")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.json")
+	formatter := m2json.Formatter{}
+	fs := m2obj.NewFileSyncer(path, formatter, m2obj.WithFileMode(0600))
+	assert.Equal(t, os.FileMode(0600), fs.GetFileMode())
+	aObj := m2obj.New(m2obj.Group{"a": "a"})
+	fs.BindObject(aObj)
+	assert.NoError(t, fs.Save())
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+		fs.SetFileMode(0640)
+		assert.NoError(t, aObj.Set("b", "b"))
+		info, err = os.Stat(path)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+	}
+	// no temp file is left
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(files))
+
+	// a failed saving keeps the old file
+	fs.SetFilePath(filepath.Join(dir, "none", "test.json"))
+	assert.Error(t, fs.Save())
+	fs.SetFilePath(path)
+	fileBytes, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	fileObj, err := formatter.Unmarshal(fileBytes)
+	assert.NoError(t, err)
+	assert.Equal(t, aObj.Staticize(), fileObj.Staticize())
+}
+
+func TestFileSyncer_m2json_ChangeDetection(t *testing.T) {
+	dir, err := ioutil.TempDir("", "m2obj")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.json")
+	formatter := m2json.Formatter{}
+	cObj := m2obj.New(m2obj.Group{"a": float64(1)})
+	fs := m2obj.NewFileSyncer(path, formatter, m2obj.WithAutoSaveTiming(-1), m2obj.WithAutoLoadTiming(20))
+	fs.BindObject(cObj)
+	assert.NoError(t, fs.Save())
+	var count int32
+	_, err = cObj.Watch("", func(ev m2obj.ChangeEvent) {
+		atomic.AddInt32(&count, 1)
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, fs.Start(context.Background()))
+	defer fs.Close()
+	wait := func() {
+		time.Sleep(200 * time.Millisecond)
+	}
+
+	// touched only
+	future := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(path, future, future))
+	wait()
+	assert.Equal(t, int32(0), atomic.LoadInt32(&count))
+	// the same content in another format
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{ "a" : 1 }`), 0644))
+	wait()
+	assert.Equal(t, int32(0), atomic.LoadInt32(&count))
+	// changed
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"a": 2}`), 0644))
+	wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+	assert.Equal(t, float64(2), cObj.MustGet("a").ValFloat64())
+	// saved by itself, the reloading is the same as the object
+	assert.NoError(t, cObj.Set("a", float64(3)))
+	assert.NoError(t, fs.Save())
+	wait()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&count))
+	assert.Equal(t, float64(3), cObj.MustGet("a").ValFloat64())
+	// the numbers of other types are the same as the loaded ones, and keep their types
+	assert.NoError(t, cObj.Set("port", 8080))
+	assert.NoError(t, fs.Save())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&count))
+	for _, hardLoad := range []bool{false, true} {
+		fs.SetHardLoad(hardLoad)
+		assert.NoError(t, fs.Load())
+		assert.Equal(t, int32(3), atomic.LoadInt32(&count))
+		assert.Equal(t, 8080, cObj.MustGet("port").Val())
+	}
+}
+
+func TestFileSyncer_m2json_ReloadRejected(t *testing.T) {
+	dir, err := ioutil.TempDir("", "m2obj")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.json")
+	var rejecting int32 = 1
+	rObj := m2obj.New(m2obj.Group{"a": float64(1)})
+	fs := m2obj.NewFileSyncer(path, m2json.Formatter{}, m2obj.WithAutoSaveTiming(-1), m2obj.WithValidate(func(candidate *m2obj.Object) error {
+		if atomic.LoadInt32(&rejecting) == 1 {
+			return errors.New("rejected")
+		}
+		return nil
+	}))
+	fs.BindObject(rObj)
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"a": 2}`), 0644))
+	assert.Error(t, fs.Load())
+	fs.SetAutoLoadTiming(20)
+	assert.NoError(t, fs.Start(context.Background()))
+	defer fs.Close()
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, float64(1), rObj.MustGet("a").ValFloat64())
+	// the rejected file is not regarded as loaded, so it is loaded once it is accepted
+	atomic.StoreInt32(&rejecting, 0)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, float64(2), rObj.MustGet("a").ValFloat64())
+}
+
+func TestFileSyncer_m2json_Notify(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the file change notifications are only supported on Linux")
+	}
+	dir, err := ioutil.TempDir("", "m2obj")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.json")
+	cObj := m2obj.New(m2obj.Group{"a": float64(1)})
+	fs := m2obj.NewFileSyncer(path, m2json.Formatter{}, m2obj.WithAutoLoadTiming(time.Hour.Milliseconds()), m2obj.WithNotify(true))
+	assert.True(t, fs.GetNotify())
+	fs.BindObject(cObj)
+	assert.NoError(t, fs.Save())
+	assert.NoError(t, fs.Start(context.Background()))
+	defer fs.Close()
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"a": 2}`), 0644))
+	for i := 0; i < 100 && cObj.MustGet("a").ValFloat64() != 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, float64(2), cObj.MustGet("a").ValFloat64())
+}
+
+type countingFormatter struct {
+	m2json.Formatter
+	marshalled int32
+}
+
+func (f *countingFormatter) Marshal(obj *m2obj.Object) ([]byte, error) {
+	atomic.AddInt32(&f.marshalled, 1)
+	return f.Formatter.Marshal(obj)
+}
+
+func TestFileSyncer_m2json_SaveDebounce(t *testing.T) {
+	dir, err := ioutil.TempDir("", "m2obj")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.json")
+	formatter := &countingFormatter{}
+	fs := m2obj.NewFileSyncer(path, formatter, m2obj.WithSaveDebounce(100, 0))
+	ms, maxWaitMs := fs.GetSaveDebounce()
+	assert.Equal(t, int64(100), ms)
+	assert.Equal(t, int64(0), maxWaitMs)
+	dObj := m2obj.New(m2obj.Group{})
+	fs.BindObject(dObj)
+
+	// a burst is saved once
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, dObj.Set("k"+strconv.Itoa(i), float64(i)))
+	}
+	assert.Equal(t, int32(0), atomic.LoadInt32(&formatter.marshalled))
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&formatter.marshalled))
+	fileBytes, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	fileObj, err := formatter.Unmarshal(fileBytes)
+	assert.NoError(t, err)
+	assert.Equal(t, dObj.Staticize(), fileObj.Staticize())
+
+	// the max wait
+	fs.SetSaveDebounce(100, 150)
+	atomic.StoreInt32(&formatter.marshalled, 0)
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, dObj.Set("k", float64(i)))
+		time.Sleep(20 * time.Millisecond)
+	}
+	assert.True(t, atomic.LoadInt32(&formatter.marshalled) >= 2)
+
+	// flushed by Close
+	assert.NoError(t, dObj.Set("last", true))
+	assert.NoError(t, fs.Close())
+	fileBytes, err = ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	fileObj, err = formatter.Unmarshal(fileBytes)
+	assert.NoError(t, err)
+	assert.Equal(t, dObj.Staticize(), fileObj.Staticize())
+}
+
+func TestFileSyncer_m2json_SaveOnlyDirty(t *testing.T) {
+	dir, err := ioutil.TempDir("", "m2obj")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.json")
+	formatter := &countingFormatter{}
+	fs := m2obj.NewFileSyncer(path, formatter, m2obj.WithAutoSaveTiming(20))
+	sObj := m2obj.New(m2obj.Group{"a": float64(1)})
+	fs.BindObject(sObj)
+	assert.NoError(t, fs.Start(context.Background()))
+	defer fs.Close()
+	// the newly bound object is saved once, and the file is not rewritten without changes
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&formatter.marshalled))
+	assert.NoError(t, sObj.Set("a", float64(2)))
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&formatter.marshalled))
+
+	// the debounced saving is skipped as well, if the changes are already saved
+	fs.SetAutoSaveTiming(0)
+	fs.SetSaveDebounce(50, 0)
+	assert.NoError(t, sObj.Set("a", float64(3)))
+	assert.NoError(t, fs.Save())
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&formatter.marshalled))
+}
+
+func TestFileSyncer_m2json_OnError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "m2obj")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "none", "test.json")
+	var (
+		mu   sync.Mutex
+		errs = map[string]error{}
+	)
+	fs := m2obj.NewFileSyncer(path, m2json.Formatter{}, m2obj.WithOnError(func(op string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs[op] = err
+	}))
+	eObj := m2obj.New(m2obj.Group{})
+	fs.BindObject(eObj)
+	assert.NoError(t, eObj.Set("a", 1))
+	mu.Lock()
+	assert.Error(t, errs["save"])
+	mu.Unlock()
+
+	fs.SetAutoLoadTiming(10)
+	assert.NoError(t, fs.Start(context.Background()))
+	defer fs.Close()
+	time.Sleep(100 * time.Millisecond)
+	mu.Lock()
+	assert.True(t, os.IsNotExist(errs["load"]))
+	mu.Unlock()
+}
+
+func TestFileSyncer_m2json_Validate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "m2obj")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.json")
+	schema := m2schema.MustCompile(m2obj.New(m2obj.Group{
+		"type":     "object",
+		"required": m2obj.Array{"Level"},
+		"properties": m2obj.Group{
+			"Level": m2obj.Group{"type": "integer"},
+		},
+	}))
+	var (
+		mu      sync.Mutex
+		loadErr error
+	)
+	fs := m2obj.NewFileSyncer(path, m2json.Formatter{}, m2obj.WithValidate(schema.Check), m2obj.WithOnError(func(op string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		loadErr = err
+	}))
+	vObj := m2obj.New(m2obj.Group{
+		"Level": float64(1),
+		"Name":  "app",
+	})
+	fs.BindObject(vObj)
+	assert.NoError(t, fs.Save())
+	count := 0
+	_, err = vObj.Watch("", func(ev m2obj.ChangeEvent) {
+		count++
+	})
+	assert.NoError(t, err)
+	before := vObj.Staticize()
+
+	// rejected
+	for _, hardLoad := range []bool{false, true} {
+		fs.SetHardLoad(hardLoad)
+		assert.NoError(t, ioutil.WriteFile(path, []byte(`{"Level": "debug", "Other": 1}`), 0644))
+		err = fs.Load()
+		assert.True(t, errors.Is(err, m2schema.ErrInvalid), "%v", err)
+		if hardLoad {
+			// the required key is missing
+			assert.NoError(t, ioutil.WriteFile(path, []byte(`{"Other": 1}`), 0644))
+			assert.True(t, errors.Is(fs.Load(), m2schema.ErrInvalid))
+		}
+		assert.Equal(t, before, vObj.Staticize())
+		assert.Equal(t, 0, count)
+	}
+	// the candidate of merging is checked, the kept key makes it valid
+	fs.SetHardLoad(false)
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"Other": 1}`), 0644))
+	assert.NoError(t, fs.Load())
+	assert.Equal(t, float64(1), vObj.MustGet("Other").ValFloat64())
+	// accepted with HardLoad
+	fs.SetHardLoad(true)
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"Level": 2}`), 0644))
+	assert.NoError(t, fs.Load())
+	assert.Equal(t, map[string]interface{}{"Level": float64(2)}, vObj.Staticize())
+	assert.Equal(t, 2, count)
+
+	// reported while auto loading
+	fs.SetValidate(func(candidate *m2obj.Object) error {
+		if candidate.MustGet("Level").ValFloat64() > 5 {
+			return errors.New("level too high")
+		}
+		return nil
+	})
+	fs.SetAutoLoadTiming(10)
+	assert.NoError(t, fs.Start(context.Background()))
+	defer fs.Close()
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"Level": 10}`), 0644))
+	time.Sleep(100 * time.Millisecond)
+	mu.Lock()
+	assert.EqualError(t, loadErr, "level too high")
+	mu.Unlock()
+	assert.Equal(t, float64(2), vObj.MustGet("Level").ValFloat64())
+	// tried again after the validate is relaxed
+	fs.SetValidate(nil)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, float64(10), vObj.MustGet("Level").ValFloat64())
+}
@@ -1,6 +1,7 @@
 package filesyncertest
 
 import (
+	"context"
 	"io/ioutil"
 	"testing"
 	"time"
@@ -17,7 +18,7 @@ func TestFileSyncer_m2yaml_Save(t *testing.T) {
 	assert.Error(t, fs.Save())
 	fs.BindObject(obj)
 	assert.NoError(t, fs.Save())
-	fileBytes, err := ioutil.ReadFile(filePath)
+	fileBytes, err := ioutil.ReadFile(homeFilePath)
 	assert.NoError(t, err)
 	fileObj, err := formatter.Unmarshal(fileBytes)
 	assert.NoError(t, err)
@@ -41,35 +42,37 @@ func TestFileSyncer_m2yaml_AutoSave(t *testing.T) {
 	initTestData("yml")
 	formatter := m2yaml.Formatter{}
 	fs := m2obj.NewFileSyncer(filePath, formatter)
+	assert.NoError(t, fs.Start(context.Background()))
+	defer fs.Close()
 	checkObj := func(t *testing.T) {
-		fileBytes, err := ioutil.ReadFile(filePath)
+		fileBytes, err := ioutil.ReadFile(homeFilePath)
 		assert.NoError(t, err)
 		fileObj, err := formatter.Unmarshal(fileBytes)
 		assert.NoError(t, err)
 		assert.Equal(t, allNumbersToFloat64(obj.Staticize()), allNumbersToFloat64(fileObj.Staticize()))
 	}
 	// init save
-	fs.AutoSaveTiming = 500
+	fs.SetAutoSaveTiming(500)
 	time.Sleep(waitStartTime)
 	fs.BindObject(obj)
 	time.Sleep(waitStartTime)
-	fs.AutoSaveTiming = -1
+	fs.SetAutoSaveTiming(-1)
 	time.Sleep(waitStopTime)
 	t.Run("init save", checkObj)
 	// first save
 	obj.Remove("e.ea")
-	fs.AutoSaveTiming = 500
+	fs.SetAutoSaveTiming(500)
 	time.Sleep(waitStartTime)
-	fs.AutoSaveTiming = -1
+	fs.SetAutoSaveTiming(-1)
 	time.Sleep(waitStopTime)
 	t.Run("first save", checkObj)
 	// second save
 	obj.SetVal(m2obj.Group{
 		"secondView": true,
 	})
-	fs.AutoSaveTiming = 500
+	fs.SetAutoSaveTiming(500)
 	time.Sleep(waitStartTime)
-	fs.AutoSaveTiming = -1
+	fs.SetAutoSaveTiming(-1)
 	time.Sleep(waitStopTime)
 	t.Run("second save", checkObj)
 }
@@ -80,7 +83,9 @@ func TestFileSyncer_m2yaml_AutoLoad(t *testing.T) {
 	initTestData("yml")
 	formatter := m2yaml.Formatter{}
 	fs := m2obj.NewFileSyncer(filePath, formatter)
-	fs.AutoSaveTiming = -1
+	assert.NoError(t, fs.Start(context.Background()))
+	defer fs.Close()
+	fs.SetAutoSaveTiming(-1)
 	obj2 := obj.Clone()
 	checkObjEqual := func(t *testing.T) {
 		assert.Equal(t, allNumbersToFloat64(obj.Staticize()), allNumbersToFloat64(obj2.Staticize()))
@@ -97,9 +102,9 @@ func TestFileSyncer_m2yaml_AutoLoad(t *testing.T) {
 	obj.Remove("d")
 	obj.Remove("e")
 	t.Run("first load before", checkObjNotEqual)
-	fs.AutoLoadTiming = 500
+	fs.SetAutoLoadTiming(500)
 	time.Sleep(waitStartTime)
-	fs.AutoLoadTiming = -1
+	fs.SetAutoLoadTiming(-1)
 	time.Sleep(waitStopTime)
 	t.Run("first load after", checkObjEqual)
 	// second load
@@ -109,7 +114,7 @@ func TestFileSyncer_m2yaml_AutoLoad(t *testing.T) {
 	assert.NoError(t, fs.Save())
 	obj.SetVal(obj2.Clone())
 	t.Run("second load before", checkObjEqual)
-	fs.AutoLoadTiming = 500
+	fs.SetAutoLoadTiming(500)
 	time.Sleep(waitStartTime)
 	t.Run("second load after", checkObjNotEqual)
 	t.Run("second load after content check", func(t *testing.T) {
@@ -120,7 +125,7 @@ func TestFileSyncer_m2yaml_AutoLoad(t *testing.T) {
 		assert.Equal(t, allNumbersToFloat64(expect.Staticize()), allNumbersToFloat64(obj.Staticize()))
 	})
 	// HardLoad
-	fs.HardLoad = true
+	fs.SetHardLoad(true)
 	time.Sleep(waitStartTime)
 	t.Run("second load after content check with HardLoad", func(t *testing.T) {
 		expect := m2obj.New(m2obj.Group{
@@ -172,7 +177,7 @@ func TestFileSyncer_m2yaml_AutoLoadOnChange(t *testing.T) {
 	formatter := m2yaml.Formatter{}
 	fs := m2obj.NewFileSyncer(filePath, formatter)
 	getActualFileObj := func() (fileObj *m2obj.Object) {
-		fileBytes, err := ioutil.ReadFile(filePath)
+		fileBytes, err := ioutil.ReadFile(homeFilePath)
 		assert.NoError(t, err)
 		fileObj, err = formatter.Unmarshal(fileBytes)
 		assert.NoError(t, err)
@@ -3,7 +3,6 @@ package filesyncertest
 import (
 	"os"
 	"path/filepath"
-	"runtime"
 
 	"github.com/rickonono3/m2obj"
 )
@@ -12,12 +11,16 @@ var obj *m2obj.Object
 
 var filePath string
 
+// homeDir is the temporary home directory, see TestMain
+var homeDir string
+
+// homeFilePath is where the FileSyncer should put the file of filePath
+var homeFilePath string
+
 func initTestData(format string) {
-	if runtime.GOOS == "windows" {
-		filePath = filepath.Join(os.Getenv("USERPROFILE"), "test."+format)
-	} else {
-		filePath = "~/test." + format
-	}
+	// the FileSyncer expands `~` to the home directory
+	filePath = "~/test." + format
+	homeFilePath = filepath.Join(homeDir, "test."+format)
 	obj = m2obj.New(m2obj.Group{
 		"a": "a",
 		"b": float64(2),
@@ -49,7 +52,7 @@ func initTestData(format string) {
 			float64(4),
 		},
 	})
-	_ = os.Remove(filePath)
+	_ = os.Remove(homeFilePath)
 }
 
 func allNumbersToFloat64(v interface{}) interface{} {
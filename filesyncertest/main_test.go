@@ -0,0 +1,27 @@
+package filesyncertest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"testing"
+)
+
+// TestMain points the home directory to a temporary one, so the `~` in the filePath is expanded without touching the real home.
+func TestMain(m *testing.M) {
+	home, err := ioutil.TempDir("", "m2obj-home")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	env := "HOME"
+	if runtime.GOOS == "windows" {
+		env = "USERPROFILE"
+	}
+	_ = os.Setenv(env, home)
+	homeDir = home
+	code := m.Run()
+	_ = os.RemoveAll(home)
+	os.Exit(code)
+}
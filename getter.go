@@ -0,0 +1,504 @@
+package m2obj
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"time"
+)
+
+// getVal
+//
+// Get the object located by the keyStr and get its value by the valFunc.
+// Instead of panicking, a *PathError will be reported when the object is not found or its value can not be converted to the type named `to`, which requires an object with the expected type.
+func (o *Object) getVal(keyStr string, to string, expected ObjectType, valFunc func(obj *Object) interface{}) (v interface{}, err error) {
+	p, err := parseKeyStr(keyStr)
+	if err != nil {
+		return
+	}
+	obj, err := o.GetPath(p)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if pan := recover(); pan != nil {
+			e := &PathError{
+				KeyStr:   p.keyStr,
+				Index:    p.Len() - 1,
+				Found:    obj.Type(),
+				Expected: expected,
+				Err:      errorOf(pan),
+			}
+			if !errors.Is(e.Err, ErrConvert) {
+				e.Err = newConvertErr(obj.Val(), to, "")
+			}
+			if e.Index >= 0 {
+				e.Key = p.keys[e.Index].key
+			}
+			v, err = nil, e
+		}
+	}()
+	return valFunc(obj), nil
+}
+
+// GetStr
+//
+// Get the object located by the keyStr, and get its value by ValStr. Never panic, any error will be reported as a *PathError.
+func (o *Object) GetStr(keyStr string) (string, error) {
+	v, err := o.getVal(keyStr, "string", ValueType, func(obj *Object) interface{} { return obj.ValStr() })
+	if err != nil {
+		return "", err
+	}
+	return v.(string), nil
+}
+
+// GetStrOr
+//
+// Like GetStr, but returns def when error occurred
+func (o *Object) GetStrOr(keyStr string, def string) string {
+	if v, err := o.GetStr(keyStr); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetBool
+//
+// Get the object located by the keyStr, and get its value by ValBool. Never panic, any error will be reported as a *PathError.
+func (o *Object) GetBool(keyStr string) (bool, error) {
+	v, err := o.getVal(keyStr, "bool", ValueType, func(obj *Object) interface{} { return obj.ValBool() })
+	if err != nil {
+		return false, err
+	}
+	return v.(bool), nil
+}
+
+// GetBoolOr
+//
+// Like GetBool, but returns def when error occurred
+func (o *Object) GetBoolOr(keyStr string, def bool) bool {
+	if v, err := o.GetBool(keyStr); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetByte
+//
+// Get the object located by the keyStr, and get its value by ValByte. Never panic, any error will be reported as a *PathError.
+func (o *Object) GetByte(keyStr string) (byte, error) {
+	v, err := o.getVal(keyStr, "byte", ValueType, func(obj *Object) interface{} { return obj.ValByte() })
+	if err != nil {
+		return 0, err
+	}
+	return v.(byte), nil
+}
+
+// GetByteOr
+//
+// Like GetByte, but returns def when error occurred
+func (o *Object) GetByteOr(keyStr string, def byte) byte {
+	if v, err := o.GetByte(keyStr); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetBytes
+//
+// Get the object located by the keyStr, and get its value by ValBytes. Never panic, any error will be reported as a *PathError.
+func (o *Object) GetBytes(keyStr string) ([]byte, error) {
+	v, err := o.getVal(keyStr, "[]byte", ValueType, func(obj *Object) interface{} { return obj.ValBytes() })
+	if err != nil {
+		return nil, err
+	}
+	return v.([]byte), nil
+}
+
+// GetBytesOr
+//
+// Like GetBytes, but returns def when error occurred
+func (o *Object) GetBytesOr(keyStr string, def []byte) []byte {
+	if v, err := o.GetBytes(keyStr); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetRune
+//
+// Get the object located by the keyStr, and get its value by ValRune. Never panic, any error will be reported as a *PathError.
+func (o *Object) GetRune(keyStr string) (rune, error) {
+	v, err := o.getVal(keyStr, "rune", ValueType, func(obj *Object) interface{} { return obj.ValRune() })
+	if err != nil {
+		return 0, err
+	}
+	return v.(rune), nil
+}
+
+// GetRuneOr
+//
+// Like GetRune, but returns def when error occurred
+func (o *Object) GetRuneOr(keyStr string, def rune) rune {
+	if v, err := o.GetRune(keyStr); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetRunes
+//
+// Get the object located by the keyStr, and get its value by ValRunes. Never panic, any error will be reported as a *PathError.
+func (o *Object) GetRunes(keyStr string) ([]rune, error) {
+	v, err := o.getVal(keyStr, "[]rune", ValueType, func(obj *Object) interface{} { return obj.ValRunes() })
+	if err != nil {
+		return nil, err
+	}
+	return v.([]rune), nil
+}
+
+// GetRunesOr
+//
+// Like GetRunes, but returns def when error occurred
+func (o *Object) GetRunesOr(keyStr string, def []rune) []rune {
+	if v, err := o.GetRunes(keyStr); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetInt
+//
+// Get the object located by the keyStr, and get its value by ValInt. Never panic, any error will be reported as a *PathError.
+func (o *Object) GetInt(keyStr string) (int, error) {
+	v, err := o.getVal(keyStr, "int", ValueType, func(obj *Object) interface{} { return obj.ValInt() })
+	if err != nil {
+		return 0, err
+	}
+	return v.(int), nil
+}
+
+// GetIntOr
+//
+// Like GetInt, but returns def when error occurred
+func (o *Object) GetIntOr(keyStr string, def int) int {
+	if v, err := o.GetInt(keyStr); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetInt8
+//
+// Get the object located by the keyStr, and get its value by ValInt8. Never panic, any error will be reported as a *PathError.
+func (o *Object) GetInt8(keyStr string) (int8, error) {
+	v, err := o.getVal(keyStr, "int8", ValueType, func(obj *Object) interface{} { return obj.ValInt8() })
+	if err != nil {
+		return 0, err
+	}
+	return v.(int8), nil
+}
+
+// GetInt8Or
+//
+// Like GetInt8, but returns def when error occurred
+func (o *Object) GetInt8Or(keyStr string, def int8) int8 {
+	if v, err := o.GetInt8(keyStr); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetInt16
+//
+// Get the object located by the keyStr, and get its value by ValInt16. Never panic, any error will be reported as a *PathError.
+func (o *Object) GetInt16(keyStr string) (int16, error) {
+	v, err := o.getVal(keyStr, "int16", ValueType, func(obj *Object) interface{} { return obj.ValInt16() })
+	if err != nil {
+		return 0, err
+	}
+	return v.(int16), nil
+}
+
+// GetInt16Or
+//
+// Like GetInt16, but returns def when error occurred
+func (o *Object) GetInt16Or(keyStr string, def int16) int16 {
+	if v, err := o.GetInt16(keyStr); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetInt32
+//
+// Get the object located by the keyStr, and get its value by ValInt32. Never panic, any error will be reported as a *PathError.
+func (o *Object) GetInt32(keyStr string) (int32, error) {
+	v, err := o.getVal(keyStr, "int32", ValueType, func(obj *Object) interface{} { return obj.ValInt32() })
+	if err != nil {
+		return 0, err
+	}
+	return v.(int32), nil
+}
+
+// GetInt32Or
+//
+// Like GetInt32, but returns def when error occurred
+func (o *Object) GetInt32Or(keyStr string, def int32) int32 {
+	if v, err := o.GetInt32(keyStr); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetInt64
+//
+// Get the object located by the keyStr, and get its value by ValInt64. Never panic, any error will be reported as a *PathError.
+func (o *Object) GetInt64(keyStr string) (int64, error) {
+	v, err := o.getVal(keyStr, "int64", ValueType, func(obj *Object) interface{} { return obj.ValInt64() })
+	if err != nil {
+		return 0, err
+	}
+	return v.(int64), nil
+}
+
+// GetInt64Or
+//
+// Like GetInt64, but returns def when error occurred
+func (o *Object) GetInt64Or(keyStr string, def int64) int64 {
+	if v, err := o.GetInt64(keyStr); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetUint
+//
+// Get the object located by the keyStr, and get its value by ValUint. Never panic, any error will be reported as a *PathError.
+func (o *Object) GetUint(keyStr string) (uint64, error) {
+	v, err := o.getVal(keyStr, "uint64", ValueType, func(obj *Object) interface{} { return obj.ValUint() })
+	if err != nil {
+		return 0, err
+	}
+	return v.(uint64), nil
+}
+
+// GetUintOr
+//
+// Like GetUint, but returns def when error occurred
+func (o *Object) GetUintOr(keyStr string, def uint64) uint64 {
+	if v, err := o.GetUint(keyStr); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetFloat32
+//
+// Get the object located by the keyStr, and get its value by ValFloat32. Never panic, any error will be reported as a *PathError.
+func (o *Object) GetFloat32(keyStr string) (float32, error) {
+	v, err := o.getVal(keyStr, "float32", ValueType, func(obj *Object) interface{} { return obj.ValFloat32() })
+	if err != nil {
+		return 0, err
+	}
+	return v.(float32), nil
+}
+
+// GetFloat32Or
+//
+// Like GetFloat32, but returns def when error occurred
+func (o *Object) GetFloat32Or(keyStr string, def float32) float32 {
+	if v, err := o.GetFloat32(keyStr); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetFloat64
+//
+// Get the object located by the keyStr, and get its value by ValFloat64. Never panic, any error will be reported as a *PathError.
+func (o *Object) GetFloat64(keyStr string) (float64, error) {
+	v, err := o.getVal(keyStr, "float64", ValueType, func(obj *Object) interface{} { return obj.ValFloat64() })
+	if err != nil {
+		return 0, err
+	}
+	return v.(float64), nil
+}
+
+// GetFloat64Or
+//
+// Like GetFloat64, but returns def when error occurred
+func (o *Object) GetFloat64Or(keyStr string, def float64) float64 {
+	if v, err := o.GetFloat64(keyStr); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetDuration
+//
+// Get the object located by the keyStr, and get its value by ValDuration. Never panic, any error will be reported as a *PathError.
+func (o *Object) GetDuration(keyStr string) (time.Duration, error) {
+	v, err := o.getVal(keyStr, "time.Duration", ValueType, func(obj *Object) interface{} { return obj.ValDuration() })
+	if err != nil {
+		return 0, err
+	}
+	return v.(time.Duration), nil
+}
+
+// GetDurationOr
+//
+// Like GetDuration, but returns def when error occurred
+func (o *Object) GetDurationOr(keyStr string, def time.Duration) time.Duration {
+	if v, err := o.GetDuration(keyStr); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetTime
+//
+// Get the object located by the keyStr, and get its value by ValTime. Never panic, any error will be reported as a *PathError.
+func (o *Object) GetTime(keyStr string, layouts ...string) (time.Time, error) {
+	v, err := o.getVal(keyStr, "time.Time", ValueType, func(obj *Object) interface{} { return obj.ValTime(layouts...) })
+	if err != nil {
+		return time.Time{}, err
+	}
+	return v.(time.Time), nil
+}
+
+// GetTimeOr
+//
+// Like GetTime, but returns def when error occurred
+func (o *Object) GetTimeOr(keyStr string, def time.Time, layouts ...string) time.Time {
+	if v, err := o.GetTime(keyStr, layouts...); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetURL
+//
+// Get the object located by the keyStr, and get its value by ValURL. Never panic, any error will be reported as a *PathError.
+func (o *Object) GetURL(keyStr string) (*url.URL, error) {
+	v, err := o.getVal(keyStr, "*url.URL", ValueType, func(obj *Object) interface{} { return obj.ValURL() })
+	if err != nil {
+		return nil, err
+	}
+	return v.(*url.URL), nil
+}
+
+// GetURLOr
+//
+// Like GetURL, but returns def when error occurred
+func (o *Object) GetURLOr(keyStr string, def *url.URL) *url.URL {
+	if v, err := o.GetURL(keyStr); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetIP
+//
+// Get the object located by the keyStr, and get its value by ValIP. Never panic, any error will be reported as a *PathError.
+func (o *Object) GetIP(keyStr string) (net.IP, error) {
+	v, err := o.getVal(keyStr, "net.IP", ValueType, func(obj *Object) interface{} { return obj.ValIP() })
+	if err != nil {
+		return nil, err
+	}
+	return v.(net.IP), nil
+}
+
+// GetIPOr
+//
+// Like GetIP, but returns def when error occurred
+func (o *Object) GetIPOr(keyStr string, def net.IP) net.IP {
+	if v, err := o.GetIP(keyStr); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetByteSize
+//
+// Get the object located by the keyStr, and get its value by ValByteSize. Never panic, any error will be reported as a *PathError.
+func (o *Object) GetByteSize(keyStr string) (uint64, error) {
+	v, err := o.getVal(keyStr, "byte size", ValueType, func(obj *Object) interface{} { return obj.ValByteSize() })
+	if err != nil {
+		return 0, err
+	}
+	return v.(uint64), nil
+}
+
+// GetByteSizeOr
+//
+// Like GetByteSize, but returns def when error occurred
+func (o *Object) GetByteSizeOr(keyStr string, def uint64) uint64 {
+	if v, err := o.GetByteSize(keyStr); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetStringSlice
+//
+// Get the object located by the keyStr, and get its value by ValStringSlice. Never panic, any error will be reported as a *PathError.
+func (o *Object) GetStringSlice(keyStr string) ([]string, error) {
+	v, err := o.getVal(keyStr, "[]string", ArrayType, func(obj *Object) interface{} { return obj.ValStringSlice() })
+	if err != nil {
+		return nil, err
+	}
+	return v.([]string), nil
+}
+
+// GetStringSliceOr
+//
+// Like GetStringSlice, but returns def when error occurred
+func (o *Object) GetStringSliceOr(keyStr string, def []string) []string {
+	if v, err := o.GetStringSlice(keyStr); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetIntSlice
+//
+// Get the object located by the keyStr, and get its value by ValIntSlice. Never panic, any error will be reported as a *PathError.
+func (o *Object) GetIntSlice(keyStr string) ([]int, error) {
+	v, err := o.getVal(keyStr, "[]int", ArrayType, func(obj *Object) interface{} { return obj.ValIntSlice() })
+	if err != nil {
+		return nil, err
+	}
+	return v.([]int), nil
+}
+
+// GetIntSliceOr
+//
+// Like GetIntSlice, but returns def when error occurred
+func (o *Object) GetIntSliceOr(keyStr string, def []int) []int {
+	if v, err := o.GetIntSlice(keyStr); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetStringMap
+//
+// Get the object located by the keyStr, and get its value by ValStringMap. Never panic, any error will be reported as a *PathError.
+func (o *Object) GetStringMap(keyStr string) (map[string]string, error) {
+	v, err := o.getVal(keyStr, "map[string]string", GroupType, func(obj *Object) interface{} { return obj.ValStringMap() })
+	if err != nil {
+		return nil, err
+	}
+	return v.(map[string]string), nil
+}
+
+// GetStringMapOr
+//
+// Like GetStringMap, but returns def when error occurred
+func (o *Object) GetStringMapOr(keyStr string, def map[string]string) map[string]string {
+	if v, err := o.GetStringMap(keyStr); err == nil {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,84 @@
+package m2obj
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObject_Getters(t *testing.T) {
+	obj := New(Group{
+		"server": Group{
+			"host":  "localhost",
+			"port":  8080,
+			"debug": true,
+			"rate":  0.5,
+			"hosts": Array{"a", "b"},
+		},
+		"nil": nil,
+	})
+	// found
+	str, err := obj.GetStr("server.host")
+	assert.NoError(t, err)
+	assert.Equal(t, "localhost", str)
+	port, err := obj.GetInt("server.port")
+	assert.NoError(t, err)
+	assert.Equal(t, 8080, port)
+	debug, err := obj.GetBool("server.debug")
+	assert.NoError(t, err)
+	assert.True(t, debug)
+	rate, err := obj.GetFloat64("server.rate")
+	assert.NoError(t, err)
+	assert.Equal(t, 0.5, rate)
+	host, err := obj.GetStr("server.hosts.[-1]")
+	assert.NoError(t, err)
+	assert.Equal(t, "b", host)
+	assert.Equal(t, int8(-1), obj.GetInt8Or("server.missing", -1))
+	assert.Equal(t, int16(8080), obj.GetInt16Or("server.port", 0))
+	assert.Equal(t, int32(8080), obj.GetInt32Or("server.port", 0))
+	assert.Equal(t, int64(8080), obj.GetInt64Or("server.port", 0))
+	assert.Equal(t, uint64(8080), obj.GetUintOr("server.port", 0))
+	assert.Equal(t, float32(8080), obj.GetFloat32Or("server.port", 0))
+	assert.Equal(t, byte('l'), obj.GetByteOr("server.host.x", 'l'))
+	assert.Equal(t, []byte("localhost"), obj.GetBytesOr("server.host", nil))
+	assert.Equal(t, []rune("localhost"), obj.GetRunesOr("server.host", nil))
+	assert.Equal(t, 'x', obj.GetRuneOr("server.host", 'x'))
+	assert.Equal(t, 8080, obj.MustGet("server.port").GetIntOr("", 0))
+
+	// defaults
+	assert.Equal(t, 80, obj.GetIntOr("server.missing", 80))
+	assert.Equal(t, 80, obj.GetIntOr("server.host", 80))
+	assert.Equal(t, 80, obj.GetIntOr("server", 80))
+	assert.Equal(t, 80, obj.GetIntOr("nil", 80))
+	assert.Equal(t, 80, obj.GetIntOr(`server."`, 80))
+	assert.Equal(t, "def", obj.GetStrOr("server.hosts.[5]", "def"))
+	assert.Equal(t, true, obj.GetBoolOr("server.port.x", true))
+	assert.Equal(t, 1.5, obj.GetFloat64Or("server.host", 1.5))
+
+	// errors
+	assert.NotPanics(t, func() {
+		_, err = obj.GetInt("server.missing")
+		assert.True(t, errors.Is(err, ErrKeyNotFound))
+		_, err = obj.GetInt("server.hosts.[5]")
+		assert.True(t, errors.Is(err, ErrIndexOverflow))
+		_, err = obj.GetInt(`server."`)
+		assert.True(t, errors.Is(err, ErrInvalidKeyStr))
+		_, err = obj.GetInt("server.host")
+		assert.True(t, errors.Is(err, ErrConvert))
+		_, err = obj.GetBool("nil")
+		assert.True(t, errors.Is(err, ErrConvert))
+	})
+	_, err = obj.GetInt("server")
+	var pathErr *PathError
+	if assert.True(t, errors.As(err, &pathErr)) {
+		assert.Equal(t, "server", pathErr.KeyStr)
+		assert.Equal(t, 0, pathErr.Index)
+		assert.Equal(t, "server", pathErr.Key)
+		assert.Equal(t, GroupType, pathErr.Found)
+		assert.Equal(t, ValueType, pathErr.Expected)
+		assert.Equal(t, convertErr{From: "*m2obj.groupData", To: "int"}, pathErr.Err)
+	}
+	_, err = obj.GetInt("server.host")
+	assert.EqualError(t, err, "can not convert value from {string} to {int}: invalid syntax, at key {host} (#1) of keyStr {server.host}")
+}
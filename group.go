@@ -5,17 +5,26 @@ package m2obj
 // Loop for all key-value pairs in the group, foreach calls `do`.
 //
 // Stops when do returns a non-nil err and return it.
+//
+// The key-value pairs are taken before looping, so it is safe to change the group in `do`.
 func (o *Object) GroupForeach(do func(key string, obj *Object) error) (err error) {
-	switch o.val.(type) {
-	case *groupData:
-		for k, obj := range *o.val.(*groupData) {
-			if err = do(k, obj); err != nil {
-				break
+	var grp groupData
+	func() {
+		defer o.rLock()()
+		switch o.val.(type) {
+		case *groupData:
+			grp = make(groupData, len(*o.val.(*groupData)))
+			for k, obj := range *o.val.(*groupData) {
+				grp[k] = obj
 			}
+		default:
+			panic(invalidTypeErr(""))
+		}
+	}()
+	for k, obj := range grp {
+		if err = do(k, obj); err != nil {
+			break
 		}
-		o.buildParentLink(o.parent)
-	default:
-		panic(invalidTypeErr(""))
 	}
 	return
 }
@@ -23,46 +32,101 @@ func (o *Object) GroupForeach(do func(key string, obj *Object) error) (err error
 // GroupMerge **!!! ONLY FOR GROUP OBJECT**
 //
 // Merges two GROUP Object recursively. All already exists array and value objects in o will be replaced (forced == true and there is a key with the same name exists in o2) or reserved (forced == false), other object in o2 will be added into o.
+//
+// The merging is reported to the watchers as an OpSet change of o.
 func (o *Object) GroupMerge(o2 *Object, forced bool) (err error) {
-	return o.groupMerge(o2, forced, true)
+	// take a snapshot of o2 first, o2 may be in the same tree with o.
+	o2 = o2.Clone()
+	err = o.write(func() {
+		c := o.beginChange(nil, OpSet, o)
+		if err := o.groupMergeData(o2, forced); err != nil {
+			panic(err)
+		}
+		c.end(o)
+	})
+	return
+}
+
+// MergePatch
+//
+// Applies a JSON Merge Patch (RFC 7386) to the object:
+//   A nil value in the patch removes the key.
+//   A Group in the patch is merged into the object recursively, and the object is changed into a Group first if it is not.
+//   Everything else (including an Array) replaces the value.
+//
+// Unlike GroupMerge, the merging is reported to the watchers as one OpSet change of o, and the patch can be any object.
+//
+// Example:
+//
+//   // {"a": 1, "b": {"c": 2, "d": 3}} -> {"b": {"c": 4, "d": 3}, "e": [5]}
+//   obj.MergePatch(m2obj.New(m2obj.Group{
+//     "a": nil,
+//     "b": m2obj.Group{"c": 4},
+//     "e": m2obj.Array{5},
+//   }))
+func (o *Object) MergePatch(patch *Object) {
+	// take a snapshot of patch first, patch may be in the same tree with o.
+	if patch != nil {
+		patch = patch.Clone()
+	}
+	o.mustWrite(func() {
+		c := o.beginChange(nil, OpSet, o)
+		o.mergePatch(patch)
+		o.buildParentLink(o.parent)
+		c.end(o)
+	})
+}
+
+// mergePatch
+//
+// MergePatch without locking and change reporting. The children of o are kept if they are not removed or replaced.
+func (o *Object) mergePatch(patch *Object) {
+	patchGrp, ok := patch.valOrNil().(*groupData)
+	if !ok {
+		o.val = patch.valOrNil()
+		return
+	}
+	if !o.isGroup() {
+		o.val = &groupData{}
+	}
+	grp := *o.val.(*groupData)
+	for key, patchObj := range *patchGrp {
+		if patchObj == nil || patchObj.val == nil {
+			delete(grp, key)
+		} else if obj, ok := grp[key]; ok && obj != nil {
+			obj.mergePatch(patchObj)
+		} else {
+			obj = New(nil)
+			obj.mergePatch(patchObj)
+			grp[key] = obj
+		}
+	}
 }
 
-func (o *Object) groupMerge(o2 *Object, forced bool, needCallOnChange bool) (err error) {
+// groupMergeData
+//
+// GroupMerge without locking and change reporting.
+//
+// The merging is done in place, so the children of o are kept and all references to them are still valid.
+func (o *Object) groupMergeData(o2 *Object, forced bool) (err error) {
 	switch o.val.(type) {
 	case *groupData: // Group
 		switch o2.val.(type) {
 		case *groupData: // Group
-			newObj := o.Clone()
-			err = o2.GroupForeach(func(key string, o2obj *Object) error {
-				if newObj.Has(key) {
-					o1obj := newObj.MustGet(key)
-					// o1obj type check
-					switch o1obj.val.(type) {
-					case *groupData:
-						// o2obj type check
-						switch o2obj.val.(type) {
-						case *groupData:
-							// Merge two sub group
-							return o1obj.GroupMerge(o2obj, forced)
-						default:
-							if forced {
-								return newObj.Set(key, o2obj)
-							}
-						}
-					default:
-						if forced {
-							return newObj.Set(key, o2obj)
-						}
+			grp := *o.val.(*groupData)
+			for key, o2obj := range *o2.val.(*groupData) {
+				if o1obj, ok := grp[key]; ok && o1obj != nil {
+					if o1obj.isGroup() && o2obj != nil && o2obj.isGroup() {
+						// Merge two sub group
+						_ = o1obj.groupMergeData(o2obj, forced)
+					} else if forced {
+						o1obj.setVal(o2obj.valOrNil())
 					}
-				} else {
-					return newObj.Set(key, o2obj)
+				} else if !ok || forced {
+					grp[key] = o2obj
 				}
-				return nil
-			})
-			if err == nil {
-				o.setVal(newObj, needCallOnChange)
-				o.buildParentLink(o.parent)
 			}
+			o.buildParentLink(o.parent)
 			return
 		default: // Array or Value
 			return invalidTypeErr("")
@@ -144,3 +144,70 @@ func TestObject_GroupMerge(t *testing.T) {
 		},
 	}).Staticize(), grp1.Staticize())
 }
+
+func TestObject_MergePatch(t *testing.T) {
+	obj := New(Group{
+		"a": 1,
+		"b": Group{
+			"c": 2,
+			"d": 3,
+		},
+		"arr":   Array{1, 2},
+		"value": "v",
+	})
+	b := obj.MustGet("b")
+	var events []ChangeEvent
+	_, err := obj.Watch("", func(ev ChangeEvent) {
+		events = append(events, ev)
+	})
+	assert.NoError(t, err)
+	obj.MergePatch(New(Group{
+		"a": nil,
+		"b": Group{
+			"c": 4,
+			"x": nil,
+		},
+		"arr": Array{3},
+		"value": Group{
+			"v": Group{
+				"w": 1,
+				"n": nil,
+			},
+		},
+		"new":     Group{"n": nil},
+		"missing": nil,
+	}))
+	want := map[string]interface{}{
+		"b": map[string]interface{}{
+			"c": 4,
+			"d": 3,
+		},
+		"arr": []interface{}{3},
+		"value": map[string]interface{}{
+			"v": map[string]interface{}{
+				"w": 1,
+			},
+		},
+		"new": map[string]interface{}{},
+	}
+	assert.Equal(t, want, obj.Staticize())
+	// the kept children are still valid
+	assert.Equal(t, b, obj.MustGet("b"))
+	assert.Equal(t, obj, b.Parent())
+	assert.Equal(t, obj.MustGet("value"), obj.MustGet("value.v.w").Parent().Parent())
+	// coalesced
+	if assert.Equal(t, 1, len(events)) {
+		assert.Equal(t, "", events[0].KeyStr)
+		assert.Equal(t, OpSet, events[0].Op)
+		assert.Equal(t, want, events[0].New)
+	}
+
+	// not a Group
+	obj.MustGet("b").MergePatch(New(Array{1}))
+	assert.Equal(t, []interface{}{1}, obj.MustGet("b").Staticize()["list"])
+	obj.MustGet("b").MergePatch(nil)
+	assert.True(t, obj.MustGet("b").IsNil())
+	// a patch in the same tree
+	obj.MustGet("new").MergePatch(obj.MustGet("value"))
+	assert.Equal(t, obj.MustGet("value").Staticize(), obj.MustGet("new").Staticize())
+}
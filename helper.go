@@ -1,76 +1,120 @@
 package m2obj
 
-import (
-	"strings"
-)
-
-// split splits the keyStr to keys.
-func split(keyStr string) (keys []string) {
-	keys = make([]string, 0)
-	if keyStr = strings.TrimSpace(keyStr); keyStr == "" {
-		return
-	}
-	return strings.Split(keyStr, ".")
-}
+import "reflect"
+
+// maxArrGap is the max number of nil Objects filled into an Array by dig, so that a large index never eats up the memory.
+const maxArrGap = 1 << 16
 
-// splitAndDig digs into current object in-depth assigned by `keyStr`, until it gets the last element and returns it.
+// dig digs into current object in-depth assigned by the path, until it gets the last element and returns it.
 //
-// Set `createLost` to true if you want to create lost keys in the `keyStr`.
-// All of lost middle keys of `keyStr` will be checked:
-//   If it is marked as an Array (There is an `[index]` key behind it), send panic always.
+// Set `createLost` to true if you want to create lost keys in the path.
+// All of lost middle keys of the path will be checked:
+//   If it is marked as an Array (There is an `[index]` key behind it), create as an Array Object.
 //   Else, create as a Group Object.
-// The last key of `keyStr` just lost will be created as an empty Value Object. You can do something by yourself.
+// The last key of the path just lost will be created as an empty Value Object. You can do something by yourself.
+// When `createLost` is true, there are some other keys regarded as lost:
+//   The `[+]` key of an Array, a new element will be pushed into the Array for it.
+//   The `[index]` key behind the end of an Array, the Array will be extended to the index with at most maxArrGap nil Objects.
+//   The middle key of an Object maintaining nil, the Object will be changed into an Array or a Group.
 //
 // The func panic at:
-//     1. the key is not found and `createLost` is false
-//     2. the key is middle of `keyStr` and has an object type neither *groupData nor *arrayData
+//     1. the key is not found and `createLost` is false, or the gap to fill is larger than maxArrGap
+//     2. the key is middle of the path and has an object type neither *groupData nor *arrayData
 //     3. the key behind an Array Object key doesn't satisfy the rule with ArrayName.[index]
-func splitAndDig(current *Object, keyStr string, createLost bool) (obj, objParent *Object) {
-	tObjParent := current.Parent()
+// with a *PathError.
+func dig(current *Object, p Path, createLost bool) (obj, objParent *Object) {
+	keys := p.keys
+	tObjParent := current.parent
 	tObj := current
-	keys := split(keyStr)
-	for i, key := range keys {
-		if key == "" {
-			continue
-		}
+	for i, item := range keys {
+		key := item.key
 		tObjParent = tObj
+		if createLost && tObj != nil && tObj.val == nil { // a nil middle object is regarded as lost
+			checkLostGaps(p, i)
+			tObj.val = newLostContainer(item)
+		}
 		// Once the code runs here, the tObj means the parent of the param key.
 		// After this switch, the tObj will be the object self assigned by the param key.
-		switch tObj.val.(type) {
+		switch tObj.valOrNil().(type) {
 		case *groupData:
-			if next, ok := (*tObj.val.(*groupData))[key]; ok { // the key exists
+			if next, ok := (*tObj.val.(*groupData))[key]; ok && (next != nil || !createLost) { // the key exists
 				tObj = next
 			} else if createLost { // not exists but can be created
+				checkLostGaps(p, i+1)
 				mapObj := *tObj.val.(*groupData)
-				if i != len(keys)-1 { // is a middle key
-					if arrCheckIndexFormat(keys[i+1]) { // is Array
-						panic(invalidTypeErr(keyStr))
-					} else { // is Group
-						mapObj[key] = New(groupData{})
-					}
-				} else { // is the last key
-					mapObj[key] = New(nil)
-				}
+				mapObj[key] = newLostObject(keys, i)
 				tObj = mapObj[key]
 			} else { // not found and panic
-				panic(invalidKeyStrErr(keyStr))
+				panic(newPathError(p, i, tObj, keyNotFoundErr("")))
 			}
 		case *arrayData:
-			if index, err := tObj.arrCheckIndexKey(key, keyStr); err == nil {
-				tObj = (*tObj.val.(*arrayData))[index]
+			arr := tObj.val.(*arrayData)
+			if createLost && (item.isPush || item.isIndex && item.index >= len(*arr)) { // push a new element
+				if item.isIndex && item.index-len(*arr) > maxArrGap {
+					panic(newPathError(p, i, tObj, indexOverflowErr{item.index}))
+				}
+				checkLostGaps(p, i+1)
+				for item.isIndex && len(*arr) < item.index { // fill the gap
+					*arr = append(*arr, New(nil))
+				}
+				*arr = append(*arr, newLostObject(keys, i))
+				tObj = (*arr)[len(*arr)-1]
+			} else if index, err := tObj.arrCheckIndexKey(item); err == nil {
+				if (*arr)[index] == nil && createLost {
+					checkLostGaps(p, i+1)
+					(*arr)[index] = newLostObject(keys, i)
+				}
+				tObj = (*arr)[index]
 			} else {
-				panic(err)
+				panic(newPathError(p, i, tObj, err))
 			}
 		default:
-			panic(invalidTypeErr(key))
+			panic(newPathError(p, i, tObj, invalidTypeErr("")))
+		}
+		if createLost && tObj.parent != tObjParent { // link the created object at once, so that it can be located before the parent links are rebuilt
+			tObj.parent = tObjParent
+			tObj.setTree(tObjParent.getTree())
 		}
 	}
 	return tObj, tObjParent
 }
 
+// checkLostGaps
+//
+// Panics with a *PathError if any of the keys from keys[from] is an index more than maxArrGap, which will be in a newly created (so empty) Array.
+// It is called before creating the first lost object, so nothing is created by a failing dig.
+func checkLostGaps(p Path, from int) {
+	for j := from; j < len(p.keys); j++ {
+		if item := p.keys[j]; item.isIndex && item.index > maxArrGap {
+			panic(newPathError(p, j, New(Array{}), indexOverflowErr{item.index}))
+		}
+	}
+}
+
+// newLostObject
+//
+// Creates the object for the lost key keys[i], see dig.
+func newLostObject(keys []keyItem, i int) *Object {
+	if i == len(keys)-1 { // is the last key
+		return New(nil)
+	}
+	return New(newLostContainer(keys[i+1]))
+}
+
+// newLostContainer
+//
+// Creates the value of a lost middle object, by the key behind it.
+func newLostContainer(next keyItem) interface{} {
+	if next.isArrayKey() { // is Array
+		return &arrayData{}
+	}
+	// is Group
+	return &groupData{}
+}
+
 // The process to make sure the param v is one of [*groupData, *arrayData, UnknownValue].
 // All other recognizable types will be transform as:
-//   Object and *Object get their value and trans again
+//   Object and *Object are deeply cloned with their trees locked for reading, and get the value of the clone
 //   groupData and arrayData trans to the pointer to them.
 //   Group and Array trans to the object-safe types (groupData and arrayData) to them
 func getDeepestValue(v interface{}) interface{} {
@@ -78,9 +122,10 @@ func getDeepestValue(v interface{}) interface{} {
 	for {
 		switch tv.(type) {
 		case Object:
-			tv = tv.(Object).val
+			obj := tv.(Object)
+			tv = lockedClone(&obj).val
 		case *Object:
-			tv = tv.(*Object).val
+			tv = lockedClone(tv.(*Object)).val
 		case groupData:
 			group := tv.(groupData)
 			return &group
@@ -100,9 +145,211 @@ func getDeepestValue(v interface{}) interface{} {
 		case Array:
 			tv = transArrayToArrayData(tv.(Array))
 		default:
-			return tv
+			if t, ok := transReflect(tv, false); ok {
+				tv = t
+			} else {
+				return tv
+			}
+		}
+	}
+}
+
+// lockedClone
+//
+// Like clone, but locks the tree for reading. The Objects from other trees must be cloned before they are moved into a tree,
+// or the two trees would share the children with different locks.
+func lockedClone(o *Object) *Object {
+	defer o.rLock()()
+	return o.clone()
+}
+
+// detachValue
+//
+// Transforms the value by getDeepestValue before locking o, the Objects in the value (maybe in the same tree with o) can not be cloned after it.
+func detachValue(value interface{}) interface{} {
+	return getDeepestValue(value)
+}
+
+// transReflect
+//
+// Transforms the maps with string (or interface{}) keys to Group, and the slices and arrays (except []byte and []rune) to Array by reflection, recursively.
+// When `structs` is true, the structs are transformed to Group by their exported fields, the pointers are dereferenced, and the nil pointers, maps and slices become nil. See FromStruct.
+//
+// ok is false when v can not be transformed, and v should be kept as a Value.
+func transReflect(v interface{}, structs bool) (t interface{}, ok bool) {
+	return (&transformer{structs: structs}).trans(v)
+}
+
+// transformer
+//
+// The state of a transReflect, which finds out the cycles (like a struct pointing to itself) by the maps, slices and pointers being transformed.
+// A cycle is transformed to nil, and marked by the `cycle`.
+type transformer struct {
+	structs  bool
+	visiting map[transVisit]bool
+	cycle    bool
+}
+
+// transVisit
+//
+// Identifies a map, slice or pointer being transformed, the length distinguishes the slices sharing the same array.
+type transVisit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// enter
+//
+// Marks rv (a non-nil map, slice or pointer) as being transformed, and returns the func to unmark it.
+// Returns false if rv is already being transformed, which means a cycle.
+func (tr *transformer) enter(rv reflect.Value) (leave func(), ok bool) {
+	visit := transVisit{ptr: rv.Pointer(), typ: rv.Type()}
+	if rv.Kind() == reflect.Slice {
+		visit.len = rv.Len()
+	}
+	if tr.visiting[visit] {
+		tr.cycle = true
+		return nil, false
+	}
+	if tr.visiting == nil {
+		tr.visiting = make(map[transVisit]bool)
+	}
+	tr.visiting[visit] = true
+	return func() {
+		delete(tr.visiting, visit)
+	}, true
+}
+
+// trans
+//
+// See transReflect.
+func (tr *transformer) trans(v interface{}) (t interface{}, ok bool) {
+	switch v.(type) {
+	case Object, *Object:
+		return v, false
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Ptr:
+		if tr.structs && rv.IsNil() { // like the nil pointers, the nil maps and slices of a struct are nil
+			return nil, true
+		}
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		if kind := rv.Type().Key().Kind(); kind != reflect.String && kind != reflect.Interface {
+			return v, false
+		}
+		leave, ok := tr.enter(rv)
+		if !ok {
+			return nil, true
+		}
+		defer leave()
+		grp := make(Group, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			key, err := toString(iter.Key().Interface())
+			if err != nil {
+				return v, false
+			}
+			grp[key] = tr.elem(iter.Value().Interface())
+		}
+		return grp, true
+	case reflect.Slice, reflect.Array:
+		if kind := rv.Type().Elem().Kind(); kind == reflect.Uint8 || kind == reflect.Int32 { // []byte and []rune are Values
+			return v, false
+		}
+		if rv.Kind() == reflect.Slice {
+			leave, ok := tr.enter(rv)
+			if !ok {
+				return nil, true
+			}
+			defer leave()
+		}
+		arr := make(Array, rv.Len())
+		for i := range arr {
+			arr[i] = tr.elem(rv.Index(i).Interface())
+		}
+		return arr, true
+	case reflect.Struct:
+		if !tr.structs || isValueStruct(rv.Type()) {
+			return v, false
+		}
+		grp := Group{}
+		tr.fields(rv, grp)
+		return grp, true
+	case reflect.Ptr:
+		if !tr.structs {
+			return v, false
+		}
+		leave, ok := tr.enter(rv)
+		if !ok {
+			return nil, true
 		}
+		defer leave()
+		return tr.elem(rv.Elem().Interface()), true
 	}
+	return v, false
+}
+
+// elem
+//
+// Likes trans, but returns v itself when it can not be transformed.
+func (tr *transformer) elem(v interface{}) interface{} {
+	if t, ok := tr.trans(v); ok {
+		return t
+	}
+	return v
+}
+
+// fields
+//
+// Puts the exported fields of the struct rv into grp, with the keys named by fieldKey.
+// The embedded structs without a tag are flattened, and the zero fields with the `omitempty` tag option are skipped.
+func (tr *transformer) fields(rv reflect.Value, grp Group) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		name, tagged := fieldKey(field)
+		if name == "-" {
+			continue
+		}
+		fv := rv.Field(i)
+		if field.Anonymous && !tagged {
+			if fv.Kind() == reflect.Ptr && fv.Type().Elem().Kind() == reflect.Struct {
+				if fv.IsNil() {
+					continue
+				}
+				leave, ok := tr.enter(fv)
+				if !ok { // an embedded pointer to the struct itself
+					continue
+				}
+				tr.fields(fv.Elem(), grp)
+				leave()
+				continue
+			}
+			if fv.Kind() == reflect.Struct {
+				tr.fields(fv, grp)
+				continue
+			}
+		}
+		if field.PkgPath != "" { // unexported
+			continue
+		}
+		if fieldOmitEmpty(field) && fv.IsZero() {
+			continue
+		}
+		grp[name] = tr.elem(fv.Interface())
+	}
+}
+
+// isValueStruct
+//
+// Returns if the struct type should be kept as a Value, such as time.Time. They are the structs implementing encoding.TextMarshaler or fmt.Stringer.
+func isValueStruct(t reflect.Type) bool {
+	pt := reflect.PtrTo(t)
+	return pt.Implements(textMarshalerType) || pt.Implements(stringerType)
 }
 
 func transArrayToArrayData(array Array) *arrayData {
@@ -114,9 +361,9 @@ func transArrayToArrayData(array Array) *arrayData {
 }
 
 func transGroupToGroupData(group Group) *groupData {
-	data := New(groupData{})
+	data := groupData{}
 	for k, v := range group {
-		_ = data.Set(k, getDeepestValue(v))
+		data[k] = New(getDeepestValue(v))
 	}
-	return data.val.(*groupData)
+	return &data
 }
@@ -0,0 +1,199 @@
+package m2obj
+
+import "reflect"
+
+// history
+//
+// The undo/redo history and the named snapshots of an object, the states are staticized values (see Staticize).
+type history struct {
+	// the max length of the undo list, 0 means the undo/redo is disabled
+	limit     int
+	undo      []interface{}
+	redo      []interface{}
+	snapshots map[string]interface{}
+	// the state before the current writing, see captureHistory
+	pending  interface{}
+	captured bool
+	// the restoring by Undo and Redo is not recorded as a new change
+	restoring bool
+}
+
+// commit
+//
+// Records the captured state into the undo list and clears the redo list, if the writing is done and o is really changed by it.
+func (h *history) commit(o *Object, done bool) {
+	if done && !reflect.DeepEqual(h.pending, o.snapshot()) {
+		h.undo = append(h.undo, h.pending)
+		if len(h.undo) > h.limit {
+			h.undo = append([]interface{}{}, h.undo[len(h.undo)-h.limit:]...)
+		}
+		h.redo = nil
+	}
+	h.pending, h.captured = nil, false
+}
+
+// getHistory
+//
+// Returns the history of the object, creates it if not exists. Must be called with the tree locked for writing.
+func (o *Object) getHistory() *history {
+	if o.history == nil {
+		o.history = &history{
+			snapshots: make(map[string]interface{}),
+		}
+		o.getTree().hasHistory = true
+	}
+	return o.history
+}
+
+// captureHistory
+//
+// Captures the state of the object before the first change in the current writing, it is recorded when the writing is done.
+func (o *Object) captureHistory() {
+	h := o.history
+	if h == nil || h.limit <= 0 || h.restoring || h.captured {
+		return
+	}
+	h.pending, h.captured = o.snapshot(), true
+	t := o.getTree()
+	t.captured = append(t.captured, o)
+}
+
+// captureHistoryOnPath
+//
+// Captures the histories of o, its ancestors and the existing objects on the path, before the lost objects on the path are created by dig.
+func (o *Object) captureHistoryOnPath(p Path) {
+	if !o.getTree().hasHistory {
+		return
+	}
+	for node := o.parent; node != nil; node = node.parent {
+		node.captureHistory()
+	}
+	node := o
+	for _, item := range p.keys {
+		node.captureHistory()
+		switch node.valOrNil().(type) {
+		case *groupData:
+			node = (*node.val.(*groupData))[item.key]
+		case *arrayData:
+			if index, err := node.arrCheckIndexKey(item); err == nil {
+				node = (*node.val.(*arrayData))[index]
+			} else {
+				node = nil
+			}
+		default:
+			node = nil
+		}
+		if node == nil {
+			return
+		}
+	}
+	node.captureHistory()
+}
+
+// EnableHistory
+//
+// Enables the undo/redo history of the object, which keeps at most n states before the changes at or under the object.
+// Every writing method (Set, Remove, SetVal, the ArrXxx methods, GroupMerge, Batch, etc.) records one state, if it really changes the object.
+// Set n to 0 to disable the history, the recorded states are dropped.
+//
+// Example:
+//
+//   obj.EnableHistory(100)
+//   _ = obj.Set("a", 2)
+//   obj.Undo() // a is restored
+//   obj.Redo() // a is 2 again
+func (o *Object) EnableHistory(n int) {
+	if n < 0 {
+		n = 0
+	}
+	defer o.lock()()
+	h := o.getHistory()
+	h.limit = n
+	if len(h.undo) > n {
+		h.undo = append([]interface{}{}, h.undo[len(h.undo)-n:]...)
+	}
+	if n == 0 {
+		h.redo = nil
+	}
+}
+
+// Undo
+//
+// Restores the object to the state before the last change, returns false if there is nothing to undo.
+//
+// The object is restored in place, so the children kept in the state are still valid. The restoring is reported to the watchers as an OpSet change of o,
+// so a bound FileSyncer saves the restored state.
+func (o *Object) Undo() (ok bool) {
+	o.mustWrite(func() {
+		if h := o.history; h != nil && len(h.undo) > 0 {
+			state := h.undo[len(h.undo)-1]
+			h.undo = h.undo[:len(h.undo)-1]
+			h.redo = append(h.redo, o.snapshot())
+			o.restore(state, false)
+			ok = true
+		}
+	})
+	return
+}
+
+// Redo
+//
+// Restores the object to the state undone by the last Undo, returns false if there is nothing to redo.
+// Any change after the Undo clears the states to redo.
+func (o *Object) Redo() (ok bool) {
+	o.mustWrite(func() {
+		if h := o.history; h != nil && len(h.redo) > 0 {
+			state := h.redo[len(h.redo)-1]
+			h.redo = h.redo[:len(h.redo)-1]
+			h.undo = append(h.undo, o.snapshot())
+			o.restore(state, false)
+			ok = true
+		}
+	})
+	return
+}
+
+// Snapshot
+//
+// Saves the current state of the object with a name, which can be restored by RestoreSnapshot. The snapshot with the same name is replaced.
+//
+// The snapshots work without EnableHistory.
+func (o *Object) Snapshot(name string) {
+	defer o.lock()()
+	o.getHistory().snapshots[name] = o.snapshot()
+}
+
+// RestoreSnapshot
+//
+// Restores the object to the state saved by Snapshot, like Undo. Returns an error wrapping ErrKeyNotFound if there is no such snapshot.
+//
+// The restoring is recorded in the history, so it can be undone.
+func (o *Object) RestoreSnapshot(name string) (err error) {
+	return o.write(func() {
+		var state interface{}
+		ok := false
+		if o.history != nil {
+			state, ok = o.history.snapshots[name]
+		}
+		if !ok {
+			panic(keyNotFoundErr(name))
+		}
+		o.restore(state, true)
+	})
+}
+
+// restore
+//
+// Restores the object to the state in place, set record to record the restoring in the history.
+func (o *Object) restore(state interface{}, record bool) {
+	if !record {
+		o.history.restoring = true
+		defer func() {
+			o.history.restoring = false
+		}()
+	}
+	c := o.beginChange(nil, OpSet, o)
+	o.syncTo(New(state))
+	o.buildParentLink(o.parent)
+	c.end(o)
+}
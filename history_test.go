@@ -0,0 +1,187 @@
+package m2obj
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObject_UndoRedo(t *testing.T) {
+	obj := New(Group{
+		"a":   1,
+		"arr": Array{1, 2},
+		"grp": Group{"x": 1},
+	})
+	obj.EnableHistory(100)
+	var states []map[string]interface{}
+	do := func(fn func()) {
+		states = append(states, obj.Staticize())
+		fn()
+	}
+	do(func() { assert.NoError(t, obj.Set("a", 2)) })
+	do(func() { assert.NoError(t, obj.Set("new.deep.key", 3)) })
+	do(func() { assert.True(t, obj.Remove("a")) })
+	do(func() { obj.MustGet("arr").ArrPush(3) })
+	do(func() { obj.MustGet("arr").ArrInsert(0, 0) })
+	do(func() { obj.MustGet("arr").ArrRemove(1) })
+	do(func() { obj.MustGet("arr").ArrSet(0, "zero") })
+	do(func() { obj.MustGet("arr").ArrPop() })
+	do(func() { obj.MustGet("arr").ArrMerge(New(Array{4, 5})) })
+	do(func() { assert.NoError(t, obj.MustGet("grp").GroupMerge(New(Group{"y": 2}), false)) })
+	do(func() { obj.MustGet("grp.x").SetVal(Array{1}) })
+	do(func() {
+		assert.NoError(t, obj.Batch(func(tx *Object) error {
+			return tx.Set("b", 1)
+		}))
+	})
+	final := obj.Staticize()
+
+	for i := len(states) - 1; i >= 0; i-- {
+		assert.True(t, obj.Undo())
+		assert.Equal(t, states[i], obj.Staticize(), "undo #%d", i)
+	}
+	assert.False(t, obj.Undo())
+	for i := 1; i < len(states); i++ {
+		assert.True(t, obj.Redo())
+		assert.Equal(t, states[i], obj.Staticize(), "redo #%d", i)
+	}
+	assert.True(t, obj.Redo())
+	assert.Equal(t, final, obj.Staticize())
+	assert.False(t, obj.Redo())
+
+	// a new change clears the redo list
+	assert.True(t, obj.Undo())
+	assert.NoError(t, obj.Set("c", 1))
+	assert.False(t, obj.Redo())
+
+	// the failing writing is not recorded
+	before := obj.Staticize()
+	assert.Error(t, obj.Batch(func(tx *Object) error {
+		_ = tx.Set("e", 1)
+		return errors.New("test")
+	}))
+	assert.Error(t, obj.ApplyPatch([]PatchOp{
+		{Op: "add", Path: "/e", Value: 1},
+		{Op: "test", Path: "/e", Value: 2},
+	}))
+	assert.NoError(t, obj.Set("d", 1))
+	assert.True(t, obj.Undo())
+	assert.Equal(t, before, obj.Staticize())
+
+	// the parent links are rebuilt
+	assert.True(t, obj.Undo())
+	assert.Equal(t, obj, obj.MustGet("grp").Parent())
+	assert.Equal(t, obj.MustGet("grp"), obj.MustGet("grp.x").Parent())
+	assert.Equal(t, obj.MustGet("arr"), obj.MustGet("arr.[0]").Parent())
+}
+
+func TestObject_HistoryLimit(t *testing.T) {
+	obj := New(Group{"a": 0})
+	assert.False(t, obj.Undo())
+	obj.EnableHistory(3)
+	for i := 1; i <= 5; i++ {
+		assert.NoError(t, obj.Set("a", i))
+	}
+	for i := 4; i >= 2; i-- {
+		assert.True(t, obj.Undo())
+		assert.Equal(t, i, obj.MustGet("a").ValInt())
+	}
+	assert.False(t, obj.Undo())
+
+	// disabled
+	obj.EnableHistory(0)
+	assert.False(t, obj.Redo())
+	assert.NoError(t, obj.Set("a", 10))
+	assert.False(t, obj.Undo())
+}
+
+func TestObject_HistoryNoChange(t *testing.T) {
+	obj := New(Group{
+		"a":   1,
+		"grp": Group{"x": 1},
+	})
+	obj.EnableHistory(10)
+	assert.NoError(t, obj.Batch(func(tx *Object) error {
+		return nil
+	}))
+	assert.NoError(t, obj.Batch(func(tx *Object) error {
+		return tx.Set("a", 1)
+	}))
+	assert.NoError(t, obj.GroupMerge(New(Group{"grp": Group{"x": 1}}), true))
+	assert.NoError(t, obj.Set("a", 1))
+	assert.False(t, obj.Undo())
+
+	// the redo list is kept as well
+	assert.NoError(t, obj.Set("a", 2))
+	assert.True(t, obj.Undo())
+	assert.NoError(t, obj.Set("a", 1))
+	assert.True(t, obj.Redo())
+	assert.Equal(t, 2, obj.MustGet("a").ValInt())
+}
+
+func TestObject_HistoryOfChild(t *testing.T) {
+	obj := New(Group{
+		"child": Group{"a": 1},
+		"other": 1,
+	})
+	child := obj.MustGet("child")
+	child.EnableHistory(10)
+	var events []ChangeEvent
+	_, err := obj.Watch("", func(ev ChangeEvent) {
+		events = append(events, ev)
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, obj.Set("other", 2)) // not recorded by child
+	assert.NoError(t, obj.Set("child.a", 2))
+	assert.NoError(t, obj.Set("child.b.c", 3))
+	assert.True(t, child.Undo())
+	assert.Equal(t, map[string]interface{}{"a": 2}, child.Staticize())
+	assert.True(t, child.Undo())
+	assert.Equal(t, map[string]interface{}{"a": 1}, child.Staticize())
+	assert.False(t, child.Undo())
+	assert.Equal(t, 2, obj.MustGet("other").ValInt())
+
+	// the watchers are notified
+	assert.Equal(t, 5, len(events))
+	assert.Equal(t, ChangeEvent{
+		KeyStr: "child",
+		Op:     OpSet,
+		Old:    map[string]interface{}{"a": 2},
+		New:    map[string]interface{}{"a": 1},
+	}, events[4])
+}
+
+func TestObject_Snapshot(t *testing.T) {
+	obj := New(Group{
+		"version": 1,
+		"data":    Array{1, 2},
+	})
+	count := 0
+	_, err := obj.Watch("", func(ev ChangeEvent) {
+		count++
+	})
+	assert.NoError(t, err)
+	err = obj.RestoreSnapshot("before-migration")
+	assert.True(t, errors.Is(err, ErrKeyNotFound))
+
+	obj.Snapshot("before-migration")
+	assert.NoError(t, obj.Set("version", 2))
+	assert.True(t, obj.Remove("data"))
+	assert.NoError(t, obj.RestoreSnapshot("before-migration"))
+	assert.Equal(t, map[string]interface{}{
+		"version": 1,
+		"data":    []interface{}{1, 2},
+	}, obj.Staticize())
+	assert.Equal(t, obj.MustGet("data"), obj.MustGet("data.[0]").Parent())
+	assert.Equal(t, 3, count)
+
+	// the restoring can be undone
+	obj.EnableHistory(10)
+	assert.NoError(t, obj.Set("version", 3))
+	assert.NoError(t, obj.RestoreSnapshot("before-migration"))
+	assert.Equal(t, 1, obj.MustGet("version").ValInt())
+	assert.True(t, obj.Undo())
+	assert.Equal(t, 3, obj.MustGet("version").ValInt())
+}
@@ -0,0 +1,164 @@
+package m2obj
+
+import (
+	"strconv"
+	"strings"
+)
+
+// keyItem
+//
+// A fragment of the keyStr.
+type keyItem struct {
+	// the key after unquoting and unescaping
+	key string
+	// if the key is quoted or escaped, it is always a key of Group, even though it looks like `[index]`
+	literal bool
+	// if the key is formatted as `[index]`
+	isIndex bool
+	// the index parsed from `[index]`, a negative index counts from the end of the Array
+	index int
+	// if the key is `[+]` or `[]`, which means the position behind the last element of the Array
+	isPush bool
+}
+
+// isArrayKey
+//
+// Returns if the key is one of `[index]`, `[+]` and `[]`
+func (item keyItem) isArrayKey() bool {
+	return item.isIndex || item.isPush
+}
+
+// split splits the keyStr to keys.
+//
+// The keys are separated by '.', and the special characters in a key can be quoted or escaped:
+//     a."example.com".port  // a -> example.com -> port
+//     a.example\.com.port   // a -> example.com -> port
+//     a.\[0]                // a -> the key named "[0]" in the Group a
+//     a.""                  // a -> the key named "" in the Group a
+// The keys of Array are:
+//     a.[0]                 // the first element of the Array a
+//     a.[-1]                // the last element of the Array a
+//     a.[+] or a.[]         // the position behind the last element of the Array a, only used to append by Set
+// The unquoted empty keys are ignored, such as `a..b`.
+//
+// The func panic with invalidKeyStrErr (without the keyStr in it) if there is an unclosed quote or a dangling '\' in the keyStr, or an index can not be transformed to an integer.
+func split(keyStr string) (keys []keyItem) {
+	keys = make([]keyItem, 0)
+	if keyStr = strings.TrimSpace(keyStr); keyStr == "" {
+		return
+	}
+	var (
+		buf     strings.Builder
+		literal bool // the current key has quoted or escaped characters
+		quoting bool // in a quoted part
+	)
+	flush := func() {
+		if buf.Len() > 0 || literal {
+			item := keyItem{
+				key:     buf.String(),
+				literal: literal,
+			}
+			if !literal && isIndexFormat(item.key) {
+				var err error
+				if item.index, err = strconv.Atoi(item.key[1 : len(item.key)-1]); err != nil {
+					panic(invalidKeyStrErr(""))
+				}
+				item.isIndex = true
+			} else if !literal && (item.key == "[+]" || item.key == "[]") {
+				item.isPush = true
+			}
+			keys = append(keys, item)
+		}
+		buf.Reset()
+		literal = false
+	}
+	runes := []rune(keyStr)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; {
+		case r == '\\':
+			if i == len(runes)-1 {
+				panic(invalidKeyStrErr(""))
+			}
+			i++
+			buf.WriteRune(runes[i])
+			literal = true
+		case r == '"':
+			quoting = !quoting
+			literal = true
+		case r == '.' && !quoting:
+			flush()
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	if quoting {
+		panic(invalidKeyStrErr(""))
+	}
+	flush()
+	return
+}
+
+// isIndexFormat
+//
+// Checks if the key is formatted as `[index]` or `[-index]`, only the format is checked, no verifying on integer transform.
+func isIndexFormat(key string) bool {
+	if len(key) < 3 || key[0] != '[' || key[len(key)-1] != ']' {
+		return false
+	}
+	digits := key[1 : len(key)-1]
+	if digits[0] == '-' {
+		digits = digits[1:]
+	}
+	if digits == "" {
+		return false
+	}
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// JoinKeyStr
+//
+// Builds a keyStr from raw keys, all of the special characters in the keys are escaped or quoted.
+//
+// Example:
+//
+//   m2obj.JoinKeyStr("hosts", "example.com", "port") // hosts.example\.com.port
+//   m2obj.JoinKeyStr("versions", "v1.2", "[0]")      // versions.v1\.2.\[0]
+//   m2obj.JoinKeyStr("a", "", " b ")                 // a.""." b "
+func JoinKeyStr(keys ...string) string {
+	escapedKeys := make([]string, len(keys))
+	for i, key := range keys {
+		escapedKeys[i] = escapeKey(key)
+	}
+	return strings.Join(escapedKeys, ".")
+}
+
+// escapeKey
+//
+// Escapes the special characters in a single key. The key needs to be quoted if it is empty or surrounded by spaces.
+func escapeKey(key string) string {
+	var buf strings.Builder
+	needQuote := key == "" || strings.TrimSpace(key) != key
+	if needQuote {
+		buf.WriteByte('"')
+	}
+	for _, r := range key {
+		switch r {
+		case '\\', '"':
+			buf.WriteByte('\\')
+		case '.', '[':
+			if !needQuote {
+				buf.WriteByte('\\')
+			}
+		}
+		buf.WriteRune(r)
+	}
+	if needQuote {
+		buf.WriteByte('"')
+	}
+	return buf.String()
+}
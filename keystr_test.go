@@ -0,0 +1,107 @@
+package m2obj
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplit(t *testing.T) {
+	type TestData struct {
+		keyStr   string
+		wantKeys []keyItem
+	}
+	testData := []TestData{
+		{"", []keyItem{}},
+		{"a.b..c", []keyItem{{key: "a"}, {key: "b"}, {key: "c"}}},
+		{"a.[0]", []keyItem{{key: "a"}, {key: "[0]", isIndex: true}}},
+		{"a.[12].b", []keyItem{{key: "a"}, {key: "[12]", isIndex: true, index: 12}, {key: "b"}}},
+		{"a.[x]", []keyItem{{key: "a"}, {key: "[x]"}}},
+		{"a.[-1]", []keyItem{{key: "a"}, {key: "[-1]", isIndex: true, index: -1}}},
+		{"a.[-]", []keyItem{{key: "a"}, {key: "[-]"}}},
+		{"a.[+].[]", []keyItem{{key: "a"}, {key: "[+]", isPush: true}, {key: "[]", isPush: true}}},
+		{`a."[+]"`, []keyItem{{key: "a"}, {key: "[+]", literal: true}}},
+		{`a."example.com".port`, []keyItem{{key: "a"}, {key: "example.com", literal: true}, {key: "port"}}},
+		{`a.example\.com.port`, []keyItem{{key: "a"}, {key: "example.com", literal: true}, {key: "port"}}},
+		{`a.v"1.2"`, []keyItem{{key: "a"}, {key: "v1.2", literal: true}}},
+		{`a.\[0]`, []keyItem{{key: "a"}, {key: "[0]", literal: true}}},
+		{`a."[0]"`, []keyItem{{key: "a"}, {key: "[0]", literal: true}}},
+		{`a."".b`, []keyItem{{key: "a"}, {key: "", literal: true}, {key: "b"}}},
+		{`"a\"b"`, []keyItem{{key: `a"b`, literal: true}}},
+		{`a\\.b`, []keyItem{{key: `a\`, literal: true}, {key: "b"}}},
+	}
+	for _, data := range testData {
+		assert.Equal(t, data.wantKeys, split(data.keyStr), data.keyStr)
+	}
+	assert.Panics(t, func() {
+		split(`a."b`)
+	})
+	assert.Panics(t, func() {
+		split(`a.b\`)
+	})
+	assert.Panics(t, func() {
+		split("a.[99999999999999999999999]")
+	})
+}
+
+func TestJoinKeyStr(t *testing.T) {
+	assert.Equal(t, `hosts.example\.com.port`, JoinKeyStr("hosts", "example.com", "port"))
+	assert.Equal(t, `versions.v1\.2.\[0]`, JoinKeyStr("versions", "v1.2", "[0]"))
+	assert.Equal(t, `a.""." b ".\\\"`, JoinKeyStr("a", "", " b ", `\"`))
+	for _, keys := range [][]string{
+		{"a", "b"},
+		{"example.com", "[0]", `\`, `"`},
+		{"", " ", " a.b ", `"x"`},
+	} {
+		items := split(JoinKeyStr(keys...))
+		gotKeys := make([]string, len(items))
+		for i, item := range items {
+			gotKeys[i] = item.key
+		}
+		assert.Equal(t, keys, gotKeys)
+	}
+}
+
+func TestObject_EscapedKeyStr(t *testing.T) {
+	obj := New(Group{
+		"hosts": Group{
+			"example.com": Group{
+				"port": 80,
+			},
+			"[0]": "not an index",
+		},
+		"arr": Array{"a", "b"},
+	})
+	assert.Equal(t, 80, obj.MustGet(`hosts."example.com".port`).ValInt())
+	assert.Equal(t, 80, obj.MustGet(`hosts.example\.com.port`).ValInt())
+	assert.Equal(t, "not an index", obj.MustGet(`hosts.\[0]`).ValStr())
+	assert.True(t, obj.Has(JoinKeyStr("hosts", "example.com")))
+	assert.False(t, obj.Has("hosts.example.com"))
+	assert.Error(t, obj.Set(`arr."[0]"`, "c"))
+	assert.Error(t, obj.Set(`hosts."unclosed`, 1))
+	assert.False(t, obj.Has(`hosts."unclosed`))
+
+	assert.NoError(t, obj.Set(JoinKeyStr("hosts", "v1.2", "port"), 8080))
+	assert.Equal(t, map[string]interface{}{
+		"port": 8080,
+	}, obj.MustGet(`hosts."v1.2"`).Staticize())
+	assert.True(t, obj.Remove(`hosts."example.com".port`))
+	assert.True(t, obj.Remove(`hosts.example\.com`))
+	assert.True(t, obj.Remove(`hosts.\[0]`))
+	assert.Equal(t, map[string]interface{}{
+		"v1.2": map[string]interface{}{
+			"port": 8080,
+		},
+	}, obj.MustGet("hosts").Staticize())
+
+	// keys with dots in a map are kept as they are
+	obj2 := New(map[string]interface{}{
+		"a.b": 1,
+	})
+	assert.Equal(t, map[string]interface{}{
+		"a.b": 1,
+	}, obj2.Staticize())
+	assert.Equal(t, map[string]interface{}{
+		"a.b": 1,
+	}, obj2.Clone().Staticize())
+}
@@ -0,0 +1,145 @@
+package m2obj
+
+import "sync"
+
+// tree
+//
+// The state shared by all of the objects in the same object tree.
+//
+// Every public method of *Object locks the tree it belongs to, so an object tree can be read and written by multiple goroutines at the same time.
+type tree struct {
+	mutex      sync.RWMutex
+	strict     bool           // see Object.SetStrict
+	events     []pendingEvent // the changes waiting for unlocking, see Object.Watch
+	hasHistory bool           // if there is any object with history in the tree, see Object.EnableHistory
+	captured   []*Object      // the objects whose histories are captured in the current writing
+	source     interface{}    // the source of the current writing, see ChangeEvent.source
+}
+
+// defaultTree is used by objects which are not created by New (e.g. a zero Object).
+var defaultTree = &tree{}
+
+func newTree() *tree {
+	return &tree{}
+}
+
+// getTree
+//
+// Returns the tree the object belongs to.
+func (o *Object) getTree() *tree {
+	if t := o.tree; t != nil {
+		return t
+	}
+	return defaultTree
+}
+
+// setTree
+//
+// Only writes the tree when it is really changed, so that the readers locating the lock never race with a rebuilding of parent links.
+func (o *Object) setTree(t *tree) {
+	if o.tree != t {
+		o.tree = t
+		if o.history != nil {
+			t.hasHistory = true
+		}
+	}
+}
+
+// lock
+//
+// Locks the whole object tree for writing and returns the unlock func. Usually used as `defer o.lock()()`.
+//
+// The tree of an object may be changed when it is being linked into another tree, so check it again after locking.
+func (o *Object) lock() (unlock func()) {
+	for {
+		t := o.getTree()
+		t.mutex.Lock()
+		if t == o.getTree() {
+			return t.mutex.Unlock
+		}
+		t.mutex.Unlock()
+	}
+}
+
+// rLock
+//
+// Locks the whole object tree for reading and returns the unlock func. Usually used as `defer o.rLock()()`.
+func (o *Object) rLock() (rUnlock func()) {
+	for {
+		t := o.getTree()
+		t.mutex.RLock()
+		if t == o.getTree() {
+			return t.mutex.RUnlock
+		}
+		t.mutex.RUnlock()
+	}
+}
+
+// read
+//
+// Calls do with the tree locked for reading, and recovers the panic in do as the returned error.
+func (o *Object) read(do func()) (err error) {
+	defer func() {
+		if pan := recover(); pan != nil {
+			err = errorOf(pan)
+		}
+	}()
+	defer o.rLock()()
+	do()
+	return
+}
+
+// write
+//
+// Calls do with the tree locked for writing, and recovers the panic in do as the returned error.
+//
+// The ChangeEvents queued in do will be dispatched to their listeners after unlocking.
+func (o *Object) write(do func()) (err error) {
+	var events []pendingEvent
+	func() {
+		defer func() {
+			if pan := recover(); pan != nil {
+				err = errorOf(pan)
+			}
+		}()
+		defer o.lock()()
+		done := false
+		defer func() {
+			o.finishWrite(&events, done)
+		}()
+		do()
+		done = true
+	}()
+	dispatch(events)
+	return
+}
+
+// mustWrite
+//
+// Likes write, but lets the panic in do go on.
+func (o *Object) mustWrite(do func()) {
+	var events []pendingEvent
+	func() {
+		defer o.lock()()
+		done := false
+		defer func() {
+			o.finishWrite(&events, done)
+		}()
+		do()
+		done = true
+	}()
+	dispatch(events)
+}
+
+// finishWrite
+//
+// Takes the queued ChangeEvents out of the tree, and records the captured histories if the writing is done without panic.
+// Must be called before unlocking.
+func (o *Object) finishWrite(events *[]pendingEvent, done bool) {
+	t := o.getTree()
+	*events, t.events = t.events, nil
+	for _, node := range t.captured {
+		node.history.commit(node, done)
+	}
+	t.captured = nil
+}
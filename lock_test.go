@@ -0,0 +1,120 @@
+package m2obj
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObject_Concurrent(t *testing.T) {
+	obj := New(Group{
+		"a": Group{
+			"b": 1,
+		},
+		"arr": Array{1, 2, 3},
+	})
+	a := obj.MustGet("a")
+	arr := obj.MustGet("arr")
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(4)
+		go func(i int) { // writer
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				assert.NoError(t, obj.Set("a.b", j))
+				assert.NoError(t, obj.Set("c."+strconv.Itoa(i), j))
+				obj.Remove("c." + strconv.Itoa(i))
+			}
+		}(i)
+		go func() { // reader
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, _ = obj.Get("a.b")
+				_ = a.MustGet("b").ValInt()
+				_ = obj.Has("c")
+				_ = a.Parent()
+			}
+		}()
+		go func() { // array
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				arr.ArrPush(j)
+				_ = arr.ArrLen()
+				arr.ArrPop()
+			}
+		}()
+		go func() { // marshal
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				_ = obj.Staticize()
+				_ = obj.Clone()
+				assert.NoError(t, obj.GroupMerge(New(Group{
+					"d": j,
+				}), true))
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 3, arr.ArrLen())
+	assert.Equal(t, a, obj.MustGet("a.b").Parent())
+}
+
+func TestObject_ConcurrentOnChange(t *testing.T) {
+	obj := New(Group{})
+	count := 0
+	// the listener is called outside the lock, so it can read the tree.
+	_, err := obj.Watch("", func(ev ChangeEvent) {
+		_ = obj.Staticize()
+		count++
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, obj.Set("a", 1))
+	obj.SetVal(Group{"b": 2})
+	assert.True(t, obj.Remove("b"))
+	assert.Equal(t, 3, count)
+}
+
+func TestObject_ConcurrentAcrossTrees(t *testing.T) {
+	a := New(Group{
+		"c": Group{"x": 0},
+	})
+	b := New(Group{})
+	c := a.MustGet("c")
+	// the objects from another tree are cloned, the trees never share children
+	assert.NoError(t, b.Set("y", c))
+	assert.NoError(t, b.Set("z", Group{"c": c}))
+	b.MustGet("y").SetVal(c)
+	arr := New(Array{c})
+	arr.ArrPush(c)
+	arr.ArrInsert(0, c)
+	arr.ArrSet(0, Object(*c))
+	n := New(c)
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			assert.NoError(t, a.Set("c.x", i))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			assert.NoError(t, b.Set("y.x", i))
+			assert.NoError(t, b.Set("z.c.x", i))
+			assert.NoError(t, arr.Set("[1].x", i))
+			assert.NoError(t, n.Set("x", i))
+		}
+	}()
+	wg.Wait()
+	assert.Equal(t, 99, a.MustGet("c.x").ValInt())
+	assert.Equal(t, b.MustGet("y"), b.MustGet("y.x").Parent())
+	assert.Equal(t, c, a.MustGet("c.x").Parent())
+
+	// the same tree
+	assert.NoError(t, a.Set("d", c))
+	assert.NoError(t, a.Set("d.x", -1))
+	assert.Equal(t, 99, a.MustGet("c.x").ValInt())
+}
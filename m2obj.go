@@ -1,12 +1,28 @@
 package m2obj
 
 import (
-	"regexp"
+	"errors"
+	"fmt"
 	"strconv"
 )
 
 // Err Definition
 
+// The sentinel errors, use `errors.Is(err, m2obj.ErrXxx)` to check the kind of an error returned by m2obj.
+var (
+	ErrIndexOverflow = errors.New("index overflow")
+	ErrInvalidKeyStr = errors.New("invalid key string")
+	ErrKeyNotFound   = errors.New("key not found")
+	ErrUnknownType   = errors.New("unknown ObjectType")
+	ErrInvalidType   = errors.New("invalid ObjectType")
+	ErrNoBoundObj    = errors.New("no bound object")
+	ErrConvert       = errors.New("can not convert value")
+	ErrInvalidPatch  = errors.New("invalid patch")
+	ErrTestFailed    = errors.New("patch test failed")
+	ErrSyncerStarted = errors.New("FileSyncer already started")
+	ErrSyncerClosed  = errors.New("FileSyncer closed")
+)
+
 type indexOverflowErr struct {
 	Index int
 }
@@ -15,10 +31,36 @@ func (e indexOverflowErr) Error() string {
 	return "no such index[" + strconv.Itoa(e.Index) + "]"
 }
 
+func (e indexOverflowErr) Is(target error) bool {
+	return target == ErrIndexOverflow
+}
+
 type invalidKeyStrErr string
 
 func (e invalidKeyStrErr) Error() string {
-	return "invalid key string: " + string(e)
+	if string(e) == "" {
+		return "invalid key string"
+	} else {
+		return "invalid key string: " + string(e)
+	}
+}
+
+func (e invalidKeyStrErr) Is(target error) bool {
+	return target == ErrInvalidKeyStr
+}
+
+type keyNotFoundErr string
+
+func (e keyNotFoundErr) Error() string {
+	if string(e) == "" {
+		return "key not found"
+	} else {
+		return "the key {" + string(e) + "} is not found"
+	}
+}
+
+func (e keyNotFoundErr) Is(target error) bool {
+	return target == ErrKeyNotFound
 }
 
 type unknownTypeErr string
@@ -31,6 +73,10 @@ func (e unknownTypeErr) Error() string {
 	}
 }
 
+func (e unknownTypeErr) Is(target error) bool {
+	return target == ErrUnknownType
+}
+
 type invalidTypeErr string
 
 func (e invalidTypeErr) Error() string {
@@ -41,12 +87,102 @@ func (e invalidTypeErr) Error() string {
 	}
 }
 
+func (e invalidTypeErr) Is(target error) bool {
+	return target == ErrInvalidType
+}
+
+type convertErr struct {
+	From   string
+	To     string
+	Reason string
+}
+
+func (e convertErr) Error() string {
+	msg := "can not convert value from {" + e.From + "} to {" + e.To + "}"
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+func (e convertErr) Is(target error) bool {
+	return target == ErrConvert
+}
+
+// PathError
+//
+// Reported by the methods locating objects with a keyStr (or Path), such as Get, Set and Unset. Use `errors.As` to get it.
+//
+// The Err is the underlying error, which can be checked by `errors.Is` with the sentinel errors.
+type PathError struct {
+	// the full keyStr
+	KeyStr string
+	// the index of the failing key in the keyStr, -1 if the keyStr itself is failing (e.g. can not be parsed)
+	Index int
+	// the failing key
+	Key string
+	// the type of object that the failing key is applied to
+	Found ObjectType
+	// the type of object that the failing key requires, ArrayType for `[index]` and GroupType for others
+	Expected ObjectType
+	// the underlying error
+	Err error
+}
+
+func (e *PathError) Error() string {
+	msg := e.Err.Error()
+	if e.Found != e.Expected {
+		msg += ", found " + e.Found.String() + " but expected " + e.Expected.String()
+	}
+	if e.Index < 0 {
+		return msg + ", in keyStr {" + e.KeyStr + "}"
+	}
+	return msg + ", at key {" + e.Key + "} (#" + strconv.Itoa(e.Index) + ") of keyStr {" + e.KeyStr + "}"
+}
+
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// newPathError
+//
+// Creates a PathError for the i-th key of the path, which is applied to the object found.
+func newPathError(p Path, i int, found *Object, err error) *PathError {
+	e := &PathError{
+		KeyStr: p.keyStr,
+		Index:  i,
+		Err:    err,
+	}
+	if i >= 0 && i < len(p.keys) {
+		e.Key = p.keys[i].key
+		e.Found = found.objType()
+		if p.keys[i].isArrayKey() {
+			e.Expected = ArrayType
+		} else {
+			e.Expected = GroupType
+		}
+	}
+	return e
+}
+
+// errorOf
+//
+// Converts a recovered panic to an error
+func errorOf(pan interface{}) error {
+	if err, ok := pan.(error); ok {
+		return err
+	}
+	return fmt.Errorf("%v", pan)
+}
+
 // Type Definition
 
 type Object struct {
 	val      interface{}
 	parent   *Object
-	onChange func() // used by fileSyncer
+	watchers []*watcher // see Watch
+	history  *history   // see EnableHistory
+	tree     *tree      // shared by all objects in the same tree
 }
 
 type Group map[string]interface{}
@@ -56,23 +192,11 @@ type arrayData []*Object
 
 // Method Definition
 
-// callOnChange
-//
-// Bubble the onChange event until the root element is reached
-func (o *Object) callOnChange() {
-	tObj := o
-	for tObj != nil {
-		if tObj.onChange != nil {
-			tObj.onChange()
-		}
-		tObj = tObj.parent
-	}
-}
-
 // Parent
 //
 // Returns the parent of the current object in the object tree, and returns nil when it is the root element.
 func (o *Object) Parent() *Object {
+	defer o.rLock()()
 	return o.parent
 }
 
@@ -81,6 +205,9 @@ func (o *Object) Parent() *Object {
 // Build or Rebuild the parent-child relationship for object tree
 func (o *Object) buildParentLink(parent *Object) {
 	o.parent = parent
+	if parent != nil {
+		o.setTree(parent.getTree())
+	}
 	switch o.val.(type) {
 	case *groupData:
 		grp := *o.val.(*groupData)
@@ -88,10 +215,7 @@ func (o *Object) buildParentLink(parent *Object) {
 			if grp[k] == nil {
 				continue
 			}
-			grp[k].parent = o
-			if grp[k].IsGroup() || grp[k].IsArray() {
-				grp[k].buildParentLink(o)
-			}
+			grp[k].buildParentLink(o)
 		}
 	case *arrayData:
 		arr := *o.val.(*arrayData)
@@ -99,10 +223,7 @@ func (o *Object) buildParentLink(parent *Object) {
 			if arr[i] == nil {
 				continue
 			}
-			arr[i].parent = o
-			if arr[i].IsGroup() || arr[i].IsArray() {
-				arr[i].buildParentLink(o)
-			}
+			arr[i].buildParentLink(o)
 		}
 	}
 }
@@ -110,10 +231,12 @@ func (o *Object) buildParentLink(parent *Object) {
 // Set
 //
 // Set the value for a elements located by the keyStr. The elements in the keyStr will be created automatically.
+// A lost key followed by an `[index]` is created as an Array, and an Array is extended with nil Objects when the index is behind its end.
+// A *PathError wrapping ErrIndexOverflow will be reported if the index is more than 65536 behind the end.
 //
-// An invalidKeyStrErr will be reported when the keyStr doesn't meet the agreed format.
+// A *PathError wrapping ErrInvalidKeyStr will be reported when the keyStr doesn't meet the agreed format.
 //
-// An invalidTypeErr will be reported when the actual object type does not match the tag format in the keyStr.
+// A *PathError wrapping ErrInvalidType will be reported when the actual object type does not match the tag format in the keyStr.
 //
 //
 // Example:
@@ -123,55 +246,127 @@ func (o *Object) buildParentLink(parent *Object) {
 //   obj.Set("a.b.c.d", 2)            // invalidType (a.b.c isn't a group)
 //   obj.Set("a.b.c", m2obj.Array{0}) // {a:{b:{c:[0]}}}
 //   obj.Set("a.b.c.[0]", 1)          // {a:{b:{c:[1]}}}
-//   obj.Set("a.b.c.[1]", 1)          // indexOverflow
+//   obj.Set("a.b.c.[2]", 3)          // {a:{b:{c:[1,nil,3]}}}
+//   obj.Set("a.b.c.[+]", 4)          // {a:{b:{c:[1,nil,3,4]}}}
+//   obj.Set("a.b.c.[-9]", 1)         // indexOverflow
 //   obj.Set("a.b.c.d", 1)            // invalidType
+//   obj.Set("a.d.[1].e", 5)          // {a:{b:{c:[1,nil,3,4]},d:[nil,{e:5}]}}
 //
 // For more info about keyStr, see https://github.com/rickonono3/m2obj
 func (o *Object) Set(keyStr string, value interface{}) (err error) {
-	defer func() { // recover any panic to error and return the error
-		if pan := recover(); pan != nil {
-			err = pan.(error)
+	p, err := parseKeyStr(keyStr)
+	if err != nil {
+		return
+	}
+	return o.SetPath(p, value)
+}
+
+// SetPath
+//
+// Like Set, but with a parsed Path
+func (o *Object) SetPath(p Path, value interface{}) (err error) {
+	value = detachValue(value)
+	return o.write(func() {
+		o.set(p, value)
+	})
+}
+
+// set
+//
+// Set without locking, the change is reported to the watchers. Panic when error occurred.
+func (o *Object) set(p Path, value interface{}) {
+	op := o.setOp(p)
+	o.captureHistoryOnPath(p)
+	obj, _ := dig(o, p, true)
+	c := obj.beginChange(nil, op, obj)
+	obj.setVal(value)
+	o.buildParentLink(o.parent)
+	c.end(obj)
+}
+
+// setOp
+//
+// Returns the ChangeOp of setting the Path, which is OpInsert when a new element will be pushed into an Array.
+func (o *Object) setOp(p Path) ChangeOp {
+	if p.Len() == 0 {
+		return OpSet
+	}
+	last := p.keys[p.Len()-1]
+	if last.isPush {
+		return OpInsert
+	}
+	if last.isIndex && last.index >= 0 {
+		parent, err := o.get(p.parent())
+		if err != nil || parent.val == nil || parent.isArray() && last.index >= parent.arrLen() { // the Array will be created or extended
+			return OpInsert
 		}
-	}()
-	obj, _ := splitAndDig(o, keyStr, true)
-	obj.setVal(value, true)
-	o.buildParentLink(o.Parent())
-	obj.callOnChange()
-	return
+	}
+	return OpSet
 }
 
 // SetIfHas
 //
 // See Set and Has
 func (o *Object) SetIfHas(keyStr string, value interface{}) (err error) {
-	if o.Has(keyStr) {
-		return o.Set(keyStr, value)
+	p, err := parseKeyStr(keyStr)
+	if err != nil {
+		return
 	}
-	return nil
+	value = detachValue(value)
+	return o.write(func() {
+		if o.has(p) {
+			o.set(p, value)
+		}
+	})
 }
 
 // SetIfNotHas
 //
 // See Set and Has
 func (o *Object) SetIfNotHas(keyStr string, value interface{}) (err error) {
-	if !o.Has(keyStr) {
-		return o.Set(keyStr, value)
+	p, err := parseKeyStr(keyStr)
+	if err != nil {
+		return
 	}
-	return nil
+	value = detachValue(value)
+	return o.write(func() {
+		if !o.has(p) {
+			o.set(p, value)
+		}
+	})
 }
 
 // Get
 //
-// Get an object located by the keyStr. Any non-existing or unexpected tag in the keyStr will cause error.
+// Get an object located by the keyStr. Any non-existing or unexpected tag in the keyStr will cause a *PathError.
 //
 // For more info about keyStr, see https://github.com/rickonono3/m2obj
 func (o *Object) Get(keyStr string) (obj *Object, err error) {
+	p, err := parseKeyStr(keyStr)
+	if err != nil {
+		return
+	}
+	return o.GetPath(p)
+}
+
+// GetPath
+//
+// Like Get, but with a parsed Path
+func (o *Object) GetPath(p Path) (obj *Object, err error) {
+	defer o.rLock()()
+	return o.get(p)
+}
+
+// get
+//
+// Get without locking
+func (o *Object) get(p Path) (obj *Object, err error) {
 	defer func() {
 		if pan := recover(); pan != nil {
-			err = pan.(error)
+			err = errorOf(pan)
 		}
 	}()
-	obj, _ = splitAndDig(o, keyStr, false)
+	obj, _ = dig(o, p, false)
 	return
 }
 
@@ -186,48 +381,127 @@ func (o *Object) MustGet(keyStr string) (obj *Object) {
 	return
 }
 
+// MustGetPath
+//
+// Like GetPath, but panic when error occurred
+func (o *Object) MustGetPath(p Path) (obj *Object) {
+	var err error
+	if obj, err = o.GetPath(p); err != nil {
+		panic(err)
+	}
+	return
+}
+
 // Has
 //
 // Check if the element located by the keyStr exists.
-// If the keyStr is invalid, this func will panic.
+// If the keyStr is invalid, returns false.
 func (o *Object) Has(keyStr string) bool {
 	_, err := o.Get(keyStr)
 	return err == nil
 }
 
+// HasPath
+//
+// Like Has, but with a parsed Path
+func (o *Object) HasPath(p Path) bool {
+	_, err := o.GetPath(p)
+	return err == nil
+}
+
+// has
+//
+// Has without locking
+func (o *Object) has(p Path) bool {
+	_, err := o.get(p)
+	return err == nil
+}
+
 // Remove
 //
-// Remove the element located by the keyStr.
-func (o *Object) Remove(keyStr string) bool {
-	if keyStr == "" {
+// Remove the element located by the keyStr. The element can be a child of a Group or an element of an Array (like `arr.[1]`).
+//
+// Returns true when the element is removed or does not exist at all, and returns false when the element can not be removed. See Unset for the details.
+func (o *Object) Remove(keyStr string) (ok bool) {
+	p, err := parseKeyStr(keyStr)
+	if err != nil {
 		return false
 	}
-	if o.Has(keyStr) {
-		var (
-			key       string
-			parentObj *Object
-			reg       = regexp.MustCompile("^(.+)[.]([^.]+)$")
-		)
-		if reg.MatchString(keyStr) {
-			submatch := reg.FindStringSubmatch(keyStr)
-			key = submatch[2]
-			parentKeyStr := submatch[1]
-			parentObj, _ = splitAndDig(o, parentKeyStr, false)
-		} else {
-			key = keyStr
-			parentObj = o
+	return o.RemovePath(p)
+}
+
+// RemovePath
+//
+// Like Remove, but with a parsed Path
+func (o *Object) RemovePath(p Path) (ok bool) {
+	o.mustWrite(func() {
+		err := o.remove(p)
+		ok = err == nil || !o.has(p) // Not exists, regarded as remove successfully.
+	})
+	return
+}
+
+// Unset
+//
+// Like Remove, but reports why the element is not removed by a *PathError, which wraps:
+//
+//   ErrKeyNotFound:   the key is not found in a Group
+//   ErrIndexOverflow: the index is not found in an Array
+//   ErrInvalidType:   the keyStr is empty (the object itself can not be removed), or doesn't match the type of objects
+//   ErrInvalidKeyStr: the keyStr doesn't meet the agreed format
+//
+// Returns nil when the element is removed.
+func (o *Object) Unset(keyStr string) (err error) {
+	p, err := parseKeyStr(keyStr)
+	if err != nil {
+		return
+	}
+	return o.UnsetPath(p)
+}
+
+// UnsetPath
+//
+// Like Unset, but with a parsed Path
+func (o *Object) UnsetPath(p Path) (err error) {
+	return o.write(func() {
+		if err := o.remove(p); err != nil {
+			panic(err)
+		}
+	})
+}
+
+// remove
+//
+// Remove without locking, the change is reported to the watchers.
+func (o *Object) remove(p Path) (err error) {
+	if p.Len() == 0 {
+		return newPathError(p, -1, o, invalidTypeErr(""))
+	}
+	parentObj, err := o.get(p.parent())
+	if err != nil {
+		return
+	}
+	i := p.Len() - 1
+	item := p.keys[i]
+	switch parentObj.valOrNil().(type) {
+	case *groupData:
+		child, ok := (*parentObj.val.(*groupData))[item.key]
+		if !ok {
+			return newPathError(p, i, parentObj, keyNotFoundErr(""))
 		}
-		switch parentObj.val.(type) {
-		case *groupData:
-			delete(*parentObj.val.(*groupData), key)
-			o.callOnChange()
-			return true
-		default:
-			return false
+		c := parentObj.beginChange([]keyItem{{key: item.key}}, OpRemove, child)
+		delete(*parentObj.val.(*groupData), item.key)
+		c.end(nil)
+	case *arrayData:
+		var index int
+		if index, err = parentObj.arrCheckIndexKey(item); err != nil {
+			return newPathError(p, i, parentObj, err)
 		}
-	} else {
-		return true // Not exists, regarded as remove successfully.
+		parentObj.arrRemove(index)
+	default:
+		return newPathError(p, i, parentObj, invalidTypeErr(""))
 	}
+	return nil
 }
 
 // staticize
@@ -243,7 +517,7 @@ func (o *Object) staticize() interface{} {
 	case *groupData: // Group
 		m := make(map[string]interface{})
 		for k, v := range *o.val.(*groupData) {
-			if v == nil || v.Val() == nil {
+			if v == nil || v.val == nil {
 				m[k] = nil
 			} else {
 				m[k] = v.staticize()
@@ -253,7 +527,7 @@ func (o *Object) staticize() interface{} {
 	case *arrayData: // Array
 		m := make([]interface{}, len(*o.val.(*arrayData)))
 		for i, v := range *o.val.(*arrayData) {
-			if v == nil || v.Val() == nil {
+			if v == nil || v.val == nil {
 				m[i] = nil
 			} else {
 				m[i] = v.staticize()
@@ -261,7 +535,7 @@ func (o *Object) staticize() interface{} {
 		}
 		return m
 	default: // Value
-		if o == nil || o.Val() == nil {
+		if o == nil || o.val == nil {
 			return nil
 		} else {
 			return o.val
@@ -287,6 +561,7 @@ func (o *Object) staticize() interface{} {
 //              "val": <result of itself>
 //            }
 func (o *Object) Staticize() map[string]interface{} {
+	defer o.rLock()()
 	switch o.val.(type) {
 	case *groupData: // Group
 		return o.staticize().(map[string]interface{})
@@ -307,21 +582,40 @@ func (o *Object) Staticize() map[string]interface{} {
 //
 // Note that if you maintain pointer elements yourself in some values, these elements cannot be deep copied.
 func (o *Object) Clone() (newObj *Object) {
+	defer o.rLock()()
+	newObj = o.clone()
+	newObj.tree.strict = o.isStrict()
+	return
+}
+
+// clone
+//
+// Clone without locking
+func (o *Object) clone() (newObj *Object) {
 	switch o.val.(type) {
 	case *groupData: // Group
-		newObj = New(groupData{})
+		grp := groupData{}
 		for k, obj := range *o.val.(*groupData) {
-			_ = newObj.Set(k, obj.Clone())
+			if obj == nil {
+				grp[k] = nil
+			} else {
+				grp[k] = obj.clone()
+			}
 		}
+		newObj = New(grp)
 	case *arrayData: // Array
-		newObj = New(arrayData{})
+		arr := make(arrayData, 0, len(*o.val.(*arrayData)))
 		for _, obj := range *o.val.(*arrayData) {
-			newObj.ArrPush(obj.Clone())
+			if obj == nil {
+				arr = append(arr, nil)
+			} else {
+				arr = append(arr, obj.clone())
+			}
 		}
+		newObj = New(arr)
 	default: // Value
 		newObj = New(o.val)
 	}
-	newObj.buildParentLink(nil)
 	return
 }
 
@@ -330,6 +624,9 @@ func (o *Object) Clone() (newObj *Object) {
 // Create a new m2obj.Object with value.
 //
 // The value can be a leaked value or another Object or m2obj.Group{} or m2obj.Array{}. All of this type arguments will be automatically recognized, parsed and saved as the deepest value without any outer shell.
+// The Objects in the value are deeply cloned, so the new Object never shares children with them.
+//
+// Any map with string (or interface{}) keys is recognized as a Group, and any slice or array (except []byte and []rune) is recognized as an Array, like map[string]string and []int. Structs are kept as Values, use FromStruct to transform them.
 //
 // BTW, in m2obj project, all arguments with the type interface{} follow the above principles. That is to say, you can pass various things directly to the arguments without worrying about parsing issues. They can be normal values or Objects that encapsulates a normal value.
 func New(value interface{}) *Object {
@@ -337,7 +634,33 @@ func New(value interface{}) *Object {
 	obj := &Object{
 		val:    t,
 		parent: nil,
+		tree:   newTree(),
 	}
 	obj.buildParentLink(nil)
 	return obj
 }
+
+// FromStruct
+//
+// Create a new m2obj.Object from a struct (or a pointer to it) by reflection.
+//
+// Each exported field becomes a child, named by its `m2obj` tag, or its `json` tag, or its field name. The tag `-` skips the field, and the tag option `omitempty` skips the field with a zero value.
+// An embedded struct without a tag is flattened into its parent. The nested structs, maps, slices and pointers are transformed recursively, but the structs like time.Time (implementing encoding.TextMarshaler or fmt.Stringer) are kept as Values.
+//
+// Maps with string keys, slices and arrays are also accepted, and the structs in them are transformed as well. Note that New transforms maps, slices and arrays too, but keeps the structs as Values.
+//
+// An error wrapping ErrConvert will be reported when v is not a struct, a map, a slice or an array,
+// or it contains a cycle (like a struct pointing to itself), which can not be transformed to a tree.
+func FromStruct(v interface{}) (*Object, error) {
+	tr := &transformer{structs: true}
+	t, _ := tr.trans(v)
+	if tr.cycle {
+		return nil, newConvertErr(v, "Object", "a cycle is found")
+	}
+	switch t.(type) {
+	case Group, Array:
+		return New(t), nil
+	default:
+		return nil, newConvertErr(v, "Object", "a struct, map, slice or array is required")
+	}
+}
@@ -1,9 +1,11 @@
 package m2obj
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -24,6 +26,10 @@ func TestError(t *testing.T) {
 			invalidKeyStrErr("keyStr"),
 			"invalid key string: keyStr",
 		},
+		{
+			keyNotFoundErr("key"),
+			"the key {key} is not found",
+		},
 		{
 			unknownTypeErr("key"),
 			"the key {key} has an unknown ObjectType",
@@ -40,12 +46,79 @@ func TestError(t *testing.T) {
 			invalidTypeErr(""),
 			"invalid ObjectType",
 		},
+		{
+			invalidKeyStrErr(""),
+			"invalid key string",
+		},
+		{
+			keyNotFoundErr(""),
+			"key not found",
+		},
+		{
+			&PathError{KeyStr: "a.b", Index: 1, Key: "b", Found: GroupType, Expected: GroupType, Err: keyNotFoundErr("")},
+			"key not found, at key {b} (#1) of keyStr {a.b}",
+		},
+		{
+			&PathError{KeyStr: "a.[0]", Index: 1, Key: "[0]", Found: ValueType, Expected: ArrayType, Err: invalidTypeErr("")},
+			"invalid ObjectType, found Value but expected Array, at key {[0]} (#1) of keyStr {a.[0]}",
+		},
+		{
+			&PathError{KeyStr: `a."`, Index: -1, Err: invalidKeyStrErr("")},
+			`invalid key string, in keyStr {a."}`,
+		},
 	}
 	for _, data := range testData {
 		assert.EqualError(t, data.err, data.wantStr)
 	}
 }
 
+func TestPathError(t *testing.T) {
+	obj := New(Group{
+		"a": Group{
+			"b": 1,
+		},
+		"arr": Array{1, 2},
+	})
+	type TestData struct {
+		keyStr   string
+		sentinel error
+		want     PathError
+	}
+	testData := []TestData{
+		{"a.c", ErrKeyNotFound, PathError{KeyStr: "a.c", Index: 1, Key: "c", Found: GroupType, Expected: GroupType}},
+		{"a.b.c", ErrInvalidType, PathError{KeyStr: "a.b.c", Index: 2, Key: "c", Found: ValueType, Expected: GroupType}},
+		{"a.[0]", ErrKeyNotFound, PathError{KeyStr: "a.[0]", Index: 1, Key: "[0]", Found: GroupType, Expected: ArrayType}},
+		{"arr.[2]", ErrIndexOverflow, PathError{KeyStr: "arr.[2]", Index: 1, Key: "[2]", Found: ArrayType, Expected: ArrayType}},
+		{"arr.x", ErrInvalidType, PathError{KeyStr: "arr.x", Index: 1, Key: "x", Found: ArrayType, Expected: GroupType}},
+		{`a."b`, ErrInvalidKeyStr, PathError{KeyStr: `a."b`, Index: -1}},
+	}
+	for _, data := range testData {
+		_, err := obj.Get(data.keyStr)
+		assert.True(t, errors.Is(err, data.sentinel), data.keyStr)
+		var pathErr *PathError
+		if assert.True(t, errors.As(err, &pathErr), data.keyStr) {
+			pathErr.Err = nil
+			assert.Equal(t, data.want, *pathErr, data.keyStr)
+		}
+	}
+	// Set reports the same errors
+	err := obj.Set("a.b.c", 1)
+	assert.True(t, errors.Is(err, ErrInvalidType))
+	var pathErr *PathError
+	assert.True(t, errors.As(err, &pathErr))
+	assert.Equal(t, 2, pathErr.Index)
+	_, err = ParsePath(`a."b`)
+	assert.True(t, errors.Is(err, ErrInvalidKeyStr))
+	// sentinels are distinct
+	assert.False(t, errors.Is(err, ErrKeyNotFound))
+	assert.True(t, errors.Is(noBoundObjErr{}, ErrNoBoundObj))
+	assert.True(t, errors.Is(unknownTypeErr("x"), ErrUnknownType))
+	// non-error panics are recovered as errors
+	assert.EqualError(t, errorOf("oops"), "oops")
+	assert.EqualError(t, errorOf(42), "42")
+	assert.Equal(t, ErrKeyNotFound, errorOf(ErrKeyNotFound))
+}
+
 func TestNewAndStaticize(t *testing.T) {
 	assert.NotPanics(t, func() {
 		m := New(nil).Staticize()
@@ -206,6 +279,163 @@ func TestNewAndStaticize(t *testing.T) {
 	}
 }
 
+func TestNewReflect(t *testing.T) {
+	type testStruct struct {
+		A int
+	}
+	obj := New(map[string]interface{}{
+		"strMap": map[string]string{"a": "1", "b": "2"},
+		"ints":   []int{1, 2, 3},
+		"arr":    [2]string{"x", "y"},
+		"yaml": map[interface{}]interface{}{
+			"k":  "v",
+			1:    "one",
+			true: []interface{}{map[interface{}]interface{}{"deep": 1}},
+		},
+		"nested":  map[string][]map[string]int{"list": {{"n": 1}}},
+		"bytes":   []byte("bytes"),
+		"runes":   []rune("runes"),
+		"struct":  testStruct{A: 1},
+		"structs": []testStruct{{A: 2}},
+		"time":    time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+		"intKeys": map[int]string{1: "a"},
+	})
+	assert.Equal(t, "2", obj.MustGet("strMap.b").ValStr())
+	assert.Equal(t, 3, obj.MustGet("ints.[-1]").ValInt())
+	assert.Equal(t, "y", obj.MustGet("arr.[1]").ValStr())
+	assert.Equal(t, "v", obj.MustGet("yaml.k").ValStr())
+	assert.Equal(t, "one", obj.MustGet("yaml.1").ValStr())
+	assert.Equal(t, 1, obj.MustGet("yaml.true.[0].deep").ValInt())
+	assert.Equal(t, 1, obj.MustGet("nested.list.[0].n").ValInt())
+	assert.True(t, obj.MustGet("strMap").IsGroup())
+	assert.True(t, obj.MustGet("ints").IsArray())
+	// kept as Values
+	assert.Equal(t, []byte("bytes"), obj.MustGet("bytes").Val())
+	assert.Equal(t, []rune("runes"), obj.MustGet("runes").Val())
+	assert.Equal(t, testStruct{A: 1}, obj.MustGet("struct").Val())
+	assert.Equal(t, testStruct{A: 2}, obj.MustGet("structs.[0]").Val())
+	assert.True(t, obj.MustGet("time").IsValue())
+	assert.True(t, obj.MustGet("intKeys").IsValue())
+	assert.Equal(t, map[string]interface{}{
+		"a": "1",
+		"b": "2",
+	}, obj.MustGet("strMap").Staticize())
+	assert.Equal(t, map[string]interface{}{
+		"list": []interface{}{1, 2, 3},
+	}, obj.MustGet("ints").Staticize())
+	// Set and SetVal
+	assert.NoError(t, obj.Set("set", map[string]int{"a": 1}))
+	assert.Equal(t, 1, obj.MustGet("set.a").ValInt())
+	obj.MustGet("set").SetVal([]string{"a", "b"})
+	assert.Equal(t, "b", obj.MustGet("set.[1]").ValStr())
+	assert.Equal(t, obj.MustGet("set"), obj.MustGet("set.[1]").Parent())
+}
+
+func TestFromStruct(t *testing.T) {
+	port := 8080
+	type inner struct {
+		Port *int `m2obj:"port"`
+	}
+	type outer struct {
+		testBase
+		*inner
+		Name     string            `m2obj:"name,omitempty"`
+		Empty    string            `json:"empty,omitempty"`
+		Skipped  string            `m2obj:"-"`
+		Nil      *inner            `m2obj:"nil"`
+		Inner    inner             `m2obj:"inner"`
+		Inners   []inner           `m2obj:"inners"`
+		InnerMap map[string]*inner `m2obj:"innerMap"`
+		Time     time.Time         `m2obj:"time"`
+		private  int
+	}
+	created := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	obj, err := FromStruct(&outer{
+		testBase: testBase{Name: "base", Version: 2},
+		inner:    &inner{Port: &port},
+		Skipped:  "x",
+		Inner:    inner{Port: &port},
+		Inners:   []inner{{}, {Port: &port}},
+		InnerMap: map[string]*inner{"a": {Port: &port}},
+		Time:     created,
+		private:  1,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"name":    "base",
+		"Version": 2,
+		"port":    8080,
+		"nil":     nil,
+		"inner": map[string]interface{}{
+			"port": 8080,
+		},
+		"inners": []interface{}{
+			map[string]interface{}{"port": nil},
+			map[string]interface{}{"port": 8080},
+		},
+		"innerMap": map[string]interface{}{
+			"a": map[string]interface{}{"port": 8080},
+		},
+		"time": created,
+	}, obj.Staticize())
+
+	// round trip with Decode
+	cfg := testConfig{
+		testBase: testBase{Name: "app", Version: 1},
+		Server:   testServerConfig{Host: "localhost", Port: 80, Timeout: time.Second},
+		Tags:     []string{"a"},
+		Labels:   map[string]string{"env": "prod"},
+		Servers:  map[string]testServerConfig{"a.b": {Host: "ab"}},
+		Created:  created,
+		Data:     []byte("data"),
+	}
+	obj, err = FromStruct(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, "ab", obj.MustGet(`servers."a.b".host`).ValStr())
+	var cfg2 testConfig
+	assert.NoError(t, obj.Decode(&cfg2))
+	assert.Equal(t, cfg, cfg2)
+
+	// maps and slices of structs
+	obj, err = FromStruct([]inner{{Port: &port}})
+	assert.NoError(t, err)
+	assert.Equal(t, 8080, obj.MustGet("[0].port").ValInt())
+	// invalid
+	for _, v := range []interface{}{nil, 1, "str", (*outer)(nil), &port, created} {
+		_, err = FromStruct(v)
+		assert.True(t, errors.Is(err, ErrConvert), "%v", v)
+	}
+
+	// cycles
+	type node struct {
+		Name string
+		Next *node
+	}
+	type embedded struct {
+		*embedded
+		Name string
+	}
+	self := &node{Name: "self"}
+	self.Next = self
+	a, b := &node{Name: "a"}, &node{Name: "b"}
+	a.Next, b.Next = b, a
+	m := map[string]interface{}{"name": "m"}
+	m["self"] = m
+	e := &embedded{Name: "e"}
+	e.embedded = e
+	for _, v := range []interface{}{self, a, *b, []*node{a}, m, e} {
+		_, err = FromStruct(v)
+		assert.True(t, errors.Is(err, ErrConvert), "%T", v)
+	}
+	// shared but not a cycle
+	shared := &node{Name: "shared"}
+	obj, err = FromStruct([]*node{{Name: "x", Next: shared}, {Name: "y", Next: shared}, shared})
+	assert.NoError(t, err)
+	assert.Equal(t, "shared", obj.MustGet("[0].Next.Name").ValStr())
+	assert.Equal(t, "shared", obj.MustGet("[1].Next.Name").ValStr())
+	assert.Equal(t, "shared", obj.MustGet("[2].Name").ValStr())
+}
+
 func TestObject_SetGetRemove(t *testing.T) {
 	obj := New(groupData{
 		"a": New(Object{val: 3}),
@@ -259,7 +489,8 @@ func TestObject_SetGetRemove(t *testing.T) {
 	assert.True(t, obj.Remove("a"))
 	assert.True(t, obj.Remove("c"))
 	assert.True(t, obj.Remove("d"))
-	assert.False(t, obj.Remove("b.[0]"))
+	assert.True(t, obj.Remove("b.[0]"))
+	obj.MustGet("b").ArrUnshift(1)
 	assert.NoError(t, obj.Set("b.[2].map", nil))
 	assert.NoError(t, obj.Set("b.[2].nil", nil))
 	assert.NoError(t, obj.Set("b.[2].string", "string"))
@@ -336,6 +567,55 @@ func TestObject_SetGetRemove2(t *testing.T) {
 	}, obj.MustGet("aa.bb.cc.dd.ee").Staticize())
 }
 
+func TestObject_RemoveAndUnset(t *testing.T) {
+	obj := New(Group{
+		"a": "a",
+		"servers": Array{
+			"s0",
+			Group{
+				"name": "s1",
+			},
+			"s2",
+			"s3",
+		},
+	})
+	changedCount := 0
+	_, err := obj.Watch("", func(ev ChangeEvent) {
+		changedCount++
+	})
+	assert.NoError(t, err)
+	// array elements
+	assert.True(t, obj.Remove("servers.[2]"))
+	assert.True(t, obj.Remove("servers.[-1]"))
+	assert.NoError(t, obj.Unset("servers.[1].name"))
+	assert.NoError(t, obj.Unset("servers.[0]"))
+	assert.Equal(t, 4, changedCount)
+	assert.Equal(t, map[string]interface{}{
+		"a": "a",
+		"servers": []interface{}{
+			map[string]interface{}{},
+		},
+	}, obj.Staticize())
+	assert.Equal(t, obj.MustGet("servers"), obj.MustGet("servers.[0]").Parent())
+	// errors
+	assert.True(t, errors.Is(obj.Unset("b"), ErrKeyNotFound))
+	assert.True(t, errors.Is(obj.Unset("b.c"), ErrKeyNotFound))
+	assert.True(t, errors.Is(obj.Unset("servers.[5]"), ErrIndexOverflow))
+	assert.True(t, errors.Is(obj.Unset("servers.x"), ErrInvalidType))
+	assert.True(t, errors.Is(obj.Unset("a.x"), ErrInvalidType))
+	assert.True(t, errors.Is(obj.Unset(""), ErrInvalidType))
+	assert.True(t, errors.Is(obj.Unset(`a."`), ErrInvalidKeyStr))
+	assert.Equal(t, 4, changedCount)
+	// not exists, regarded as remove successfully
+	assert.True(t, obj.Remove("b"))
+	assert.True(t, obj.Remove("servers.[5]"))
+	assert.True(t, obj.Remove("a.x"))
+	// can not be removed
+	assert.False(t, obj.Remove(""))
+	assert.False(t, obj.Remove(`a."`))
+	assert.Equal(t, 4, changedCount)
+}
+
 func TestObject_Clone(t *testing.T) {
 	obj := New(groupData{
 		"arr": New(arrayData{
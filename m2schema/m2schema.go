@@ -0,0 +1,572 @@
+// Package m2schema validates m2obj Objects by JSON Schemas.
+//
+// The supported keywords are a subset of JSON Schema draft 2020-12:
+//
+//   type, properties, required, additionalProperties, items, enum,
+//   minimum, maximum, exclusiveMinimum, exclusiveMaximum,
+//   minLength, maxLength, pattern, minItems, maxItems,
+//   $ref (to a JSON Pointer within the document, like "#/$defs/port"), $defs
+//
+// The other keywords are ignored. A schema is an Object itself, so it can be loaded by m2json or m2yaml.
+package m2schema
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rickonono3/m2obj"
+)
+
+var (
+	ErrInvalidSchema = errors.New("invalid schema")
+	ErrInvalid       = errors.New("the object is invalid")
+)
+
+type invalidSchemaErr string
+
+func (e invalidSchemaErr) Error() string {
+	return "invalid schema: " + string(e)
+}
+
+func (e invalidSchemaErr) Is(target error) bool {
+	return target == ErrInvalidSchema
+}
+
+// Violation
+//
+// A failed keyword of the schema.
+type Violation struct {
+	// the keyStr of the invalid value, "" means the object itself
+	KeyStr string
+	// the failing keyword, like "type" or "required"
+	Keyword string
+	// the JSON Pointer of the failing keyword in the schema, like "#/properties/Debug/properties/Level/type"
+	SchemaPath string
+	Message    string
+}
+
+func (v Violation) Error() string {
+	return v.Message + ", in keyStr {" + v.KeyStr + "}"
+}
+
+// Violations
+//
+// Reported by Schema.Check, all of the violations of an object.
+type Violations []Violation
+
+func (vs Violations) Error() string {
+	msgs := make([]string, len(vs))
+	for i, v := range vs {
+		msgs[i] = v.Error()
+	}
+	return ErrInvalid.Error() + ": " + strings.Join(msgs, "; ")
+}
+
+func (vs Violations) Is(target error) bool {
+	return target == ErrInvalid
+}
+
+// Schema
+//
+// A compiled JSON Schema, which can be used by multiple goroutines at the same time.
+type Schema struct {
+	root *node
+}
+
+// node
+//
+// A compiled (sub)schema.
+type node struct {
+	pointer string
+	// the boolean schema, true accepts everything and false accepts nothing
+	always *bool
+	ref    string
+	refTo  *node
+
+	types                []string
+	enum                 []interface{}
+	minimum, maximum     *float64
+	exclMin, exclMax     *float64
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+	minItems, maxItems   *int
+	items                *node
+	properties           map[string]*node
+	required             []string
+	additional           *node
+}
+
+// Compile
+//
+// Compiles a schema Object. Returns an error wrapping ErrInvalidSchema if a keyword is malformed, or a $ref can not be resolved.
+//
+// Example:
+//
+//   schemaObj, err := m2json.Formatter{}.Unmarshal(schemaData)
+//   schema, err := m2schema.Compile(schemaObj)
+//   for _, v := range schema.Validate(obj) {
+//     log.Println(v.KeyStr, v.Message)
+//   }
+func Compile(schema *m2obj.Object) (*Schema, error) {
+	c := compiler{
+		doc:   rawValue(schema),
+		nodes: make(map[string]*node),
+	}
+	root, err := c.compile(c.doc, "#")
+	if err != nil {
+		return nil, err
+	}
+	for len(c.refs) > 0 {
+		n := c.refs[0]
+		c.refs = c.refs[1:]
+		if n.refTo, err = c.resolve(n.ref); err != nil {
+			return nil, err
+		}
+	}
+	// a $ref chain which goes back to itself never ends the validating
+	for _, n := range c.nodes {
+		next := n.refTo
+		for i := 0; next != nil; i++ {
+			if next == n || i > len(c.nodes) {
+				return nil, invalidSchemaErr("the $ref of {" + n.pointer + "} refers to itself")
+			}
+			next = next.refTo
+		}
+	}
+	return &Schema{root: root}, nil
+}
+
+// MustCompile
+//
+// Compile with panic.
+func MustCompile(schema *m2obj.Object) *Schema {
+	s, err := Compile(schema)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Validate
+//
+// Validates the object, returns all of the violations, or nil if the object is valid.
+func (s *Schema) Validate(obj *m2obj.Object) []Violation {
+	var vs []Violation
+	s.root.validate(rawValue(obj), "", &vs)
+	return vs
+}
+
+// Check
+//
+// Validate, returns a Violations error (which wraps ErrInvalid) if the object is invalid.
+func (s *Schema) Check(obj *m2obj.Object) error {
+	if vs := s.Validate(obj); len(vs) > 0 {
+		return Violations(vs)
+	}
+	return nil
+}
+
+// rawValue
+//
+// Returns the staticized value of the object, without the wrapping of Staticize.
+func rawValue(obj *m2obj.Object) interface{} {
+	m := obj.Staticize()
+	switch {
+	case obj.IsGroup():
+		return m
+	case obj.IsArray():
+		return m["list"]
+	default:
+		return m["val"]
+	}
+}
+
+type compiler struct {
+	doc   interface{}
+	nodes map[string]*node
+	// the nodes whose $ref are not resolved
+	refs []*node
+}
+
+func (c *compiler) compile(v interface{}, pointer string) (n *node, err error) {
+	if n, ok := c.nodes[pointer]; ok {
+		return n, nil
+	}
+	n = &node{pointer: pointer}
+	c.nodes[pointer] = n
+	if b, ok := v.(bool); ok {
+		n.always = &b
+		return
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, invalidSchemaErr("{" + pointer + "} is not an object or a boolean")
+	}
+	at := func(keyword string) string {
+		return pointer + "/" + escapePointer(keyword)
+	}
+	for keyword, kv := range m {
+		switch keyword {
+		case "$ref":
+			ref, ok := kv.(string)
+			if !ok {
+				return nil, invalidSchemaErr("{" + at(keyword) + "} is not a string")
+			}
+			n.ref = ref
+			c.refs = append(c.refs, n)
+		case "$defs", "definitions":
+			defs, ok := kv.(map[string]interface{})
+			if !ok {
+				return nil, invalidSchemaErr("{" + at(keyword) + "} is not an object")
+			}
+			for name, def := range defs {
+				if _, err = c.compile(def, at(keyword)+"/"+escapePointer(name)); err != nil {
+					return
+				}
+			}
+		case "type":
+			switch t := kv.(type) {
+			case string:
+				n.types = []string{t}
+			case []interface{}:
+				for _, item := range t {
+					s, ok := item.(string)
+					if !ok {
+						return nil, invalidSchemaErr("{" + at(keyword) + "} is not a string or an array of strings")
+					}
+					n.types = append(n.types, s)
+				}
+			default:
+				return nil, invalidSchemaErr("{" + at(keyword) + "} is not a string or an array of strings")
+			}
+			for _, t := range n.types {
+				switch t {
+				case "null", "boolean", "object", "array", "number", "integer", "string":
+				default:
+					return nil, invalidSchemaErr("{" + at(keyword) + "} has an unknown type {" + t + "}")
+				}
+			}
+		case "enum":
+			if n.enum, ok = kv.([]interface{}); !ok {
+				return nil, invalidSchemaErr("{" + at(keyword) + "} is not an array")
+			}
+		case "minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum":
+			f, ok := toNumber(kv)
+			if !ok {
+				return nil, invalidSchemaErr("{" + at(keyword) + "} is not a number")
+			}
+			switch keyword {
+			case "minimum":
+				n.minimum = &f
+			case "maximum":
+				n.maximum = &f
+			case "exclusiveMinimum":
+				n.exclMin = &f
+			default:
+				n.exclMax = &f
+			}
+		case "minLength", "maxLength", "minItems", "maxItems":
+			f, ok := toNumber(kv)
+			if !ok || f < 0 || f != math.Trunc(f) {
+				return nil, invalidSchemaErr("{" + at(keyword) + "} is not a non-negative integer")
+			}
+			i := int(f)
+			switch keyword {
+			case "minLength":
+				n.minLength = &i
+			case "maxLength":
+				n.maxLength = &i
+			case "minItems":
+				n.minItems = &i
+			default:
+				n.maxItems = &i
+			}
+		case "pattern":
+			s, ok := kv.(string)
+			if !ok {
+				return nil, invalidSchemaErr("{" + at(keyword) + "} is not a string")
+			}
+			if n.pattern, err = regexp.Compile(s); err != nil {
+				return nil, invalidSchemaErr("{" + at(keyword) + "} is not a valid pattern: " + err.Error())
+			}
+		case "items":
+			if n.items, err = c.compile(kv, at(keyword)); err != nil {
+				return
+			}
+		case "properties":
+			props, ok := kv.(map[string]interface{})
+			if !ok {
+				return nil, invalidSchemaErr("{" + at(keyword) + "} is not an object")
+			}
+			n.properties = make(map[string]*node, len(props))
+			for name, prop := range props {
+				if n.properties[name], err = c.compile(prop, at(keyword)+"/"+escapePointer(name)); err != nil {
+					return
+				}
+			}
+		case "required":
+			arr, ok := kv.([]interface{})
+			if !ok {
+				return nil, invalidSchemaErr("{" + at(keyword) + "} is not an array of strings")
+			}
+			for _, item := range arr {
+				s, ok := item.(string)
+				if !ok {
+					return nil, invalidSchemaErr("{" + at(keyword) + "} is not an array of strings")
+				}
+				n.required = append(n.required, s)
+			}
+		case "additionalProperties":
+			if n.additional, err = c.compile(kv, at(keyword)); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// resolve
+//
+// Finds the node of a $ref, the subschemas which are not compiled yet (e.g. under an unknown keyword) are compiled here.
+func (c *compiler) resolve(ref string) (*node, error) {
+	if ref != "#" && !strings.HasPrefix(ref, "#/") {
+		return nil, invalidSchemaErr("the $ref {" + ref + "} is not a JSON Pointer within the document")
+	}
+	if n, ok := c.nodes[ref]; ok {
+		return n, nil
+	}
+	v := c.doc
+	for _, token := range strings.Split(ref, "/")[1:] {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch cur := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if v, ok = cur[token]; !ok {
+				return nil, invalidSchemaErr("the $ref {" + ref + "} can not be resolved")
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(cur) {
+				return nil, invalidSchemaErr("the $ref {" + ref + "} can not be resolved")
+			}
+			v = cur[i]
+		default:
+			return nil, invalidSchemaErr("the $ref {" + ref + "} can not be resolved")
+		}
+	}
+	return c.compile(v, ref)
+}
+
+func (n *node) validate(v interface{}, keyStr string, vs *[]Violation) {
+	report := func(keyword, msg string) {
+		*vs = append(*vs, Violation{
+			KeyStr:     keyStr,
+			Keyword:    keyword,
+			SchemaPath: n.pointer + "/" + escapePointer(keyword),
+			Message:    msg,
+		})
+	}
+	if n.always != nil {
+		if !*n.always {
+			*vs = append(*vs, Violation{
+				KeyStr:     keyStr,
+				Keyword:    "false",
+				SchemaPath: n.pointer,
+				Message:    "no value is allowed",
+			})
+		}
+		return
+	}
+	if n.refTo != nil {
+		n.refTo.validate(v, keyStr, vs)
+	}
+	if len(n.types) > 0 && !matchType(v, n.types) {
+		report("type", "expected "+strings.Join(n.types, " or ")+", but got "+typeOf(v))
+		// the other keywords are meaningless for a value of a wrong type
+		return
+	}
+	if n.enum != nil {
+		found := false
+		for _, e := range n.enum {
+			if equal(v, e) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			report("enum", fmt.Sprintf("%v is not one of %v", v, n.enum))
+		}
+	}
+	if f, ok := toNumber(v); ok {
+		if n.minimum != nil && f < *n.minimum {
+			report("minimum", fmt.Sprintf("%v is less than %v", v, *n.minimum))
+		}
+		if n.maximum != nil && f > *n.maximum {
+			report("maximum", fmt.Sprintf("%v is greater than %v", v, *n.maximum))
+		}
+		if n.exclMin != nil && f <= *n.exclMin {
+			report("exclusiveMinimum", fmt.Sprintf("%v is not greater than %v", v, *n.exclMin))
+		}
+		if n.exclMax != nil && f >= *n.exclMax {
+			report("exclusiveMaximum", fmt.Sprintf("%v is not less than %v", v, *n.exclMax))
+		}
+	}
+	switch v := v.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if n.minLength != nil && length < *n.minLength {
+			report("minLength", fmt.Sprintf("the length %d is less than %d", length, *n.minLength))
+		}
+		if n.maxLength != nil && length > *n.maxLength {
+			report("maxLength", fmt.Sprintf("the length %d is greater than %d", length, *n.maxLength))
+		}
+		if n.pattern != nil && !n.pattern.MatchString(v) {
+			report("pattern", strconv.Quote(v)+" doesn't match the pattern {"+n.pattern.String()+"}")
+		}
+	case []interface{}:
+		if n.minItems != nil && len(v) < *n.minItems {
+			report("minItems", fmt.Sprintf("the length %d is less than %d", len(v), *n.minItems))
+		}
+		if n.maxItems != nil && len(v) > *n.maxItems {
+			report("maxItems", fmt.Sprintf("the length %d is greater than %d", len(v), *n.maxItems))
+		}
+		if n.items != nil {
+			for i, item := range v {
+				n.items.validate(item, joinKeyStr(keyStr, "["+strconv.Itoa(i)+"]"), vs)
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range n.required {
+			if _, ok := v[name]; !ok {
+				report("required", "the required key {"+name+"} is missing")
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			childKeyStr := joinKeyStr(keyStr, m2obj.JoinKeyStr(k))
+			if prop, ok := n.properties[k]; ok {
+				prop.validate(v[k], childKeyStr, vs)
+			} else if n.additional != nil {
+				if n.additional.always != nil && !*n.additional.always {
+					*vs = append(*vs, Violation{
+						KeyStr:     childKeyStr,
+						Keyword:    "additionalProperties",
+						SchemaPath: n.additional.pointer,
+						Message:    "the key {" + k + "} is not allowed",
+					})
+				} else {
+					n.additional.validate(v[k], childKeyStr, vs)
+				}
+			}
+		}
+	}
+}
+
+func joinKeyStr(keyStr, key string) string {
+	if keyStr == "" {
+		return key
+	}
+	return keyStr + "." + key
+}
+
+// escapePointer
+//
+// Escapes a token of a JSON Pointer.
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// toNumber
+//
+// Converts a value of any numeric Go type to float64.
+func toNumber(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	if f, ok := toNumber(v); ok {
+		if f == math.Trunc(f) && !math.IsInf(f, 0) {
+			return "integer"
+		}
+		return "number"
+	}
+	return reflect.TypeOf(v).String()
+}
+
+func matchType(v interface{}, types []string) bool {
+	t := typeOf(v)
+	for _, want := range types {
+		if want == t || want == "number" && t == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+// equal
+//
+// Compares two values like JSON, the numbers of different types are equal if they have the same value.
+func equal(a, b interface{}) bool {
+	switch a := a.(type) {
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, av := range a {
+			if bv, ok := b[k]; !ok || !equal(av, bv) {
+				return false
+			}
+		}
+		return true
+	}
+	if af, ok := toNumber(a); ok {
+		bf, ok := toNumber(b)
+		return ok && af == bf
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,197 @@
+package m2schema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rickonono3/m2obj"
+	"github.com/rickonono3/m2obj/m2json"
+	"github.com/rickonono3/m2obj/m2yaml"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"required": ["Debug", "Servers"],
+	"properties": {
+		"Debug": {
+			"type": "object",
+			"properties": {
+				"Level": {"type": "integer", "minimum": 0, "maximum": 5},
+				"Mode": {"enum": ["dev", "prod"]}
+			},
+			"additionalProperties": false
+		},
+		"Servers": {
+			"type": "array",
+			"minItems": 1,
+			"items": {"$ref": "#/$defs/server"}
+		},
+		"Ratio": {"type": "number", "exclusiveMinimum": 0, "exclusiveMaximum": 1}
+	},
+	"additionalProperties": {"type": "string"},
+	"$defs": {
+		"server": {
+			"type": "object",
+			"required": ["host"],
+			"properties": {
+				"host": {"type": "string", "minLength": 1, "pattern": "^[a-z0-9.-]+$"},
+				"port": {"$ref": "#/$defs/port"}
+			}
+		},
+		"port": {"type": "integer", "minimum": 1, "maximum": 65535}
+	}
+}`
+
+func TestSchema_Validate(t *testing.T) {
+	schemaObj, err := m2json.Formatter{}.Unmarshal([]byte(testSchema))
+	assert.NoError(t, err)
+	schema, err := Compile(schemaObj)
+	assert.NoError(t, err)
+
+	valid := m2obj.New(m2obj.Group{
+		"Debug": m2obj.Group{
+			"Level": 3,
+			"Mode":  "dev",
+		},
+		"Servers": m2obj.Array{
+			m2obj.Group{"host": "example.com", "port": 8080},
+			m2obj.Group{"host": "localhost"},
+		},
+		"Ratio": 0.5,
+		"Name":  "app",
+	})
+	assert.Nil(t, schema.Validate(valid))
+	assert.NoError(t, schema.Check(valid))
+
+	invalid := m2obj.New(m2obj.Group{
+		"Debug": m2obj.Group{
+			"Level": "3",
+			"Mode":  "test",
+			"Extra": true,
+		},
+		"Servers": m2obj.Array{
+			m2obj.Group{"host": "Example.com", "port": 0},
+			m2obj.Group{"port": 70000.0},
+		},
+		"Ratio":   1,
+		"key.dot": 1,
+	})
+	type Want struct {
+		keyStr  string
+		keyword string
+	}
+	var got []Want
+	for _, v := range schema.Validate(invalid) {
+		got = append(got, Want{v.KeyStr, v.Keyword})
+	}
+	assert.Equal(t, []Want{
+		{"Debug.Extra", "additionalProperties"},
+		{"Debug.Level", "type"},
+		{"Debug.Mode", "enum"},
+		{"Ratio", "exclusiveMaximum"},
+		{"Servers.[0].host", "pattern"},
+		{"Servers.[0].port", "minimum"},
+		{"Servers.[1]", "required"},
+		{"Servers.[1].port", "maximum"},
+		{`key\.dot`, "type"},
+	}, got)
+	err = schema.Check(invalid)
+	assert.True(t, errors.Is(err, ErrInvalid))
+	assert.Equal(t, 9, len(err.(Violations)))
+	assert.Equal(t, "#/properties/Debug/properties/Level/type", err.(Violations)[1].SchemaPath)
+
+	missing := m2obj.New(m2obj.Group{
+		"Servers": m2obj.Array{},
+	})
+	vs := schema.Validate(missing)
+	if assert.Equal(t, 2, len(vs)) {
+		assert.Equal(t, Violation{
+			KeyStr:     "",
+			Keyword:    "required",
+			SchemaPath: "#/required",
+			Message:    "the required key {Debug} is missing",
+		}, vs[0])
+		assert.Equal(t, "minItems", vs[1].Keyword)
+	}
+}
+
+func TestSchema_YAML(t *testing.T) {
+	schemaObj, err := m2yaml.Formatter{}.Unmarshal([]byte(`
+type: object
+properties:
+  tags:
+    type: array
+    items:
+      type: [string, "null"]
+      maxLength: 3
+    maxItems: 2
+  tree:
+    $ref: "#/$defs/node"
+$defs:
+  node:
+    type: object
+    properties:
+      children:
+        type: array
+        items:
+          $ref: "#/$defs/node"
+`))
+	assert.NoError(t, err)
+	schema := MustCompile(schemaObj)
+	obj := m2obj.New(m2obj.Group{
+		"tags": m2obj.Array{"a", nil, "abcd"},
+		"tree": m2obj.Group{
+			"children": m2obj.Array{
+				m2obj.Group{"children": m2obj.Array{1}},
+			},
+		},
+	})
+	var keyStrs []string
+	for _, v := range schema.Validate(obj) {
+		keyStrs = append(keyStrs, v.KeyStr+" "+v.Keyword)
+	}
+	assert.Equal(t, []string{
+		"tags maxItems",
+		"tags.[2] maxLength",
+		"tree.children.[0].children.[0] type",
+	}, keyStrs)
+
+	// not a Group
+	assert.Nil(t, schema.Validate(m2obj.New(m2obj.Group{})))
+	vs := MustCompile(m2obj.New(m2obj.Group{"type": "array"})).Validate(m2obj.New(1))
+	if assert.Equal(t, 1, len(vs)) {
+		assert.Equal(t, "expected array, but got integer", vs[0].Message)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	testData := []m2obj.Group{
+		{"type": "unknown"},
+		{"type": 1},
+		{"minimum": "1"},
+		{"minLength": -1},
+		{"pattern": "("},
+		{"properties": m2obj.Group{"a": 1}},
+		{"required": m2obj.Array{1}},
+		{"$ref": "#/$defs/none"},
+		{"$ref": "other.json"},
+		{"$ref": "#/$defs/a", "$defs": m2obj.Group{"a": m2obj.Group{"$ref": "#/$defs/a"}}},
+	}
+	for _, data := range testData {
+		_, err := Compile(m2obj.New(data))
+		assert.True(t, errors.Is(err, ErrInvalidSchema), "%v: %v", data, err)
+	}
+	assert.Panics(t, func() {
+		MustCompile(m2obj.New(1))
+	})
+	// boolean schemas
+	assert.Nil(t, MustCompile(m2obj.New(true)).Validate(m2obj.New(1)))
+	assert.Equal(t, 1, len(MustCompile(m2obj.New(false)).Validate(m2obj.New(1))))
+	// recursive by "#"
+	_, err := Compile(m2obj.New(m2obj.Group{
+		"properties": m2obj.Group{"child": m2obj.Group{"$ref": "#"}},
+	}))
+	assert.NoError(t, err)
+}
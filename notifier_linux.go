@@ -0,0 +1,73 @@
+//go:build linux
+// +build linux
+
+package m2obj
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// fileNotifier
+//
+// Notifies the changes of a file by inotify.
+type fileNotifier struct {
+	path string
+	// the inotify instance, it is non-blocking so that closing it stops the reading
+	file *os.File
+	// receives a value when the file may be changed
+	changed chan struct{}
+}
+
+// newFileNotifier
+//
+// Watches the directory of the file rather than the file itself, as the file is replaced by renaming when it is saved atomically.
+func newFileNotifier(path string) (*fileNotifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	if _, err = syscall.InotifyAddWatch(fd, dir, syscall.IN_CLOSE_WRITE|syscall.IN_MOVED_TO); err != nil {
+		_ = syscall.Close(fd)
+		return nil, err
+	}
+	n := &fileNotifier{
+		path:    path,
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		changed: make(chan struct{}, 1),
+	}
+	go n.read(name)
+	return n, nil
+}
+
+func (n *fileNotifier) read(name string) {
+	buf := make([]byte, 4096)
+	for {
+		l, err := n.file.Read(buf)
+		if err != nil {
+			return
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= l; {
+			ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			evName := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(ev.Len)]
+			offset += syscall.SizeofInotifyEvent + int(ev.Len)
+			if ev.Mask&syscall.IN_Q_OVERFLOW != 0 || strings.TrimRight(string(evName), "\x00") == name {
+				select {
+				case n.changed <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}
+}
+
+func (n *fileNotifier) close() {
+	_ = n.file.Close()
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+// +build !linux
+
+package m2obj
+
+import "errors"
+
+// fileNotifier
+//
+// The file change notifications are only supported on Linux, the FileSyncer falls back to the timer on the other systems.
+type fileNotifier struct {
+	path    string
+	changed chan struct{}
+}
+
+func newFileNotifier(path string) (*fileNotifier, error) {
+	return nil, errors.New("the file change notifications are not supported")
+}
+
+func (n *fileNotifier) close() {}
@@ -0,0 +1,330 @@
+package m2obj
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type invalidPatchErr string
+
+func (e invalidPatchErr) Error() string {
+	if string(e) == "" {
+		return "invalid patch"
+	} else {
+		return "invalid patch: " + string(e)
+	}
+}
+
+func (e invalidPatchErr) Is(target error) bool {
+	return target == ErrInvalidPatch
+}
+
+type testFailedErr struct{}
+
+func (e testFailedErr) Error() string {
+	return "the value is not equal to the tested one"
+}
+
+func (e testFailedErr) Is(target error) bool {
+	return target == ErrTestFailed
+}
+
+// PatchOp
+//
+// An operation of a JSON Patch (RFC 6902), whose paths are JSON Pointers (RFC 6901) like `/servers/0/port`.
+//
+// The Op is one of "add", "remove", "replace", "move", "copy" and "test". The From is only used by "move" and "copy",
+// and the Value is only used by "add", "replace" and "test".
+type PatchOp struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// MarshalJSON
+//
+// Only writes the members used by the Op, a nil Value is written as null.
+func (op PatchOp) MarshalJSON() ([]byte, error) {
+	m := map[string]interface{}{
+		"op":   op.Op,
+		"path": op.Path,
+	}
+	switch op.Op {
+	case "add", "replace", "test":
+		m["value"] = op.Value
+	case "move", "copy":
+		m["from"] = op.From
+	}
+	return json.Marshal(m)
+}
+
+// PatchError
+//
+// Reported by ApplyPatch when an operation fails. The Err is the underlying error, which can be checked by `errors.Is` with the sentinel errors.
+type PatchError struct {
+	// the index of the failing operation in the patch
+	Index int
+	// the failing operation
+	Op PatchOp
+	// the underlying error
+	Err error
+}
+
+func (e *PatchError) Error() string {
+	return e.Err.Error() + ", at operation {" + e.Op.Op + " " + e.Op.Path + "} (#" + strconv.Itoa(e.Index) + ") of the patch"
+}
+
+func (e *PatchError) Unwrap() error {
+	return e.Err
+}
+
+// ApplyPatch
+//
+// Applies a JSON Patch (RFC 6902) to the object. The `-` token of the paths means the position behind the last element of an Array.
+//
+// The patch is atomic: all of the operations are tried on a clone of the object first, so nothing is changed if any of them fails,
+// and the watchers (including a bound FileSyncer) only see the changes of a successful patch.
+//
+// A *PatchError with the failing operation will be reported, which wraps:
+//
+//   ErrInvalidPatch:  the Op is unknown, or a value is moved into itself
+//   ErrTestFailed:    the value of a "test" operation is not equal to the tested one
+//   ErrInvalidKeyStr: a path is not a valid JSON Pointer
+//   and the errors of the keyStr locating, see Unset.
+//
+// Example:
+//
+//   var ops []m2obj.PatchOp
+//   _ = json.Unmarshal([]byte(`[{"op": "replace", "path": "/db/pool/size", "value": 20}]`), &ops)
+//   err := obj.ApplyPatch(ops)
+func (o *Object) ApplyPatch(ops []PatchOp) (err error) {
+	// the values are taken before locking, they may be (or contain) objects in other trees.
+	values := make([]interface{}, len(ops))
+	for i, op := range ops {
+		values[i] = New(op.Value).snapshot()
+	}
+	return o.write(func() {
+		if err := o.clone().applyPatch(ops, values); err != nil { // dry run
+			panic(err)
+		}
+		if err := o.applyPatch(ops, values); err != nil {
+			panic(err)
+		}
+	})
+}
+
+// applyPatch
+//
+// ApplyPatch without locking and atomicity.
+func (o *Object) applyPatch(ops []PatchOp, values []interface{}) error {
+	for i, op := range ops {
+		if err := o.applyPatchOp(op, values[i]); err != nil {
+			return &PatchError{
+				Index: i,
+				Op:    op,
+				Err:   err,
+			}
+		}
+	}
+	return nil
+}
+
+func (o *Object) applyPatchOp(op PatchOp, value interface{}) (err error) {
+	defer func() {
+		if pan := recover(); pan != nil {
+			err = errorOf(pan)
+		}
+	}()
+	p, err := o.resolvePointer(op.Path)
+	if err != nil {
+		return
+	}
+	switch op.Op {
+	case "add":
+		return o.patchAdd(p, value)
+	case "remove":
+		return o.remove(p)
+	case "replace":
+		if _, err = o.get(p); err != nil {
+			return
+		}
+		if p.Len() == 0 {
+			return o.patchAdd(p, value)
+		}
+		o.set(p, value)
+		return
+	case "move", "copy":
+		var from Path
+		if from, err = o.resolvePointer(op.From); err != nil {
+			return
+		}
+		var obj *Object
+		if obj, err = o.get(from); err != nil {
+			return
+		}
+		value = obj.snapshot()
+		if op.Op == "move" {
+			if op.From == op.Path {
+				return
+			}
+			if strings.HasPrefix(op.Path, op.From+"/") {
+				return invalidPatchErr("can not move {" + op.From + "} into itself")
+			}
+			if err = o.remove(from); err != nil {
+				return
+			}
+			// the path is located again, the indices may be shifted by the removing
+			if p, err = o.resolvePointer(op.Path); err != nil {
+				return
+			}
+		}
+		return o.patchAdd(p, value)
+	case "test":
+		var obj *Object
+		if obj, err = o.get(p); err != nil {
+			return
+		}
+		if !jsonEqual(obj.snapshot(), value) {
+			return testFailedErr{}
+		}
+		return
+	default:
+		return invalidPatchErr("unknown op {" + op.Op + "}")
+	}
+}
+
+// patchAdd
+//
+// The "add" operation, inserts the value into an Array, or sets the value of a Group key or the object itself.
+// The parent of the path must exist.
+func (o *Object) patchAdd(p Path, value interface{}) error {
+	if p.Len() == 0 {
+		c := o.beginChange(nil, OpSet, o)
+		o.setVal(value)
+		c.end(o)
+		return nil
+	}
+	parent, err := o.get(p.parent())
+	if err != nil {
+		return err
+	}
+	i := p.Len() - 1
+	last := p.keys[i]
+	switch parent.valOrNil().(type) {
+	case *groupData:
+		o.set(p, value)
+	case *arrayData:
+		if last.isPush {
+			parent.arrPush(value)
+		} else if last.index <= parent.arrLen() {
+			parent.arrInsert(last.index, value)
+		} else {
+			return newPathError(p, i, parent, indexOverflowErr{last.index})
+		}
+	default:
+		return newPathError(p, i, parent, invalidTypeErr(""))
+	}
+	return nil
+}
+
+// resolvePointer
+//
+// Translates a JSON Pointer to a Path of the object. The tokens are resolved by the types of the objects on the path,
+// a token of an Array must be an index or `-`, and a token of a Group (or a non-existing object) is always a key.
+func (o *Object) resolvePointer(pointer string) (p Path, err error) {
+	if pointer == "" {
+		return
+	}
+	if pointer[0] != '/' {
+		return p, invalidKeyStrErr("the JSON Pointer {" + pointer + "} doesn't start with '/'")
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	p.keys = make([]keyItem, len(tokens))
+	current := o
+	for i, token := range tokens {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		var next *Object
+		switch current.valOrNil().(type) {
+		case *arrayData:
+			arr := *current.val.(*arrayData)
+			if token == "-" {
+				p.keys[i] = keyItem{key: "[+]", isPush: true}
+			} else if index, err := strconv.Atoi(token); err == nil && index >= 0 && strconv.Itoa(index) == token {
+				p.keys[i] = indexKeyItem(index)
+				if index < len(arr) {
+					next = arr[index]
+				}
+			} else {
+				return p, invalidKeyStrErr("the token {" + token + "} of the JSON Pointer {" + pointer + "} is not an index of an Array")
+			}
+		case *groupData:
+			p.keys[i] = keyItem{key: token, literal: true}
+			next = (*current.val.(*groupData))[token]
+		default:
+			p.keys[i] = keyItem{key: token, literal: true}
+		}
+		current = next
+	}
+	p.keyStr = joinKeyItems(p.keys)
+	return
+}
+
+// toPointer
+//
+// Translates a keyStr built by joinKeyItems to a JSON Pointer.
+func toPointer(keyStr string) string {
+	var buf strings.Builder
+	for _, item := range MustParsePath(keyStr).keys {
+		buf.WriteByte('/')
+		if item.isIndex {
+			buf.WriteString(strconv.Itoa(item.index))
+		} else {
+			buf.WriteString(strings.ReplaceAll(strings.ReplaceAll(item.key, "~", "~0"), "/", "~1"))
+		}
+	}
+	return buf.String()
+}
+
+// jsonEqual
+//
+// Compares two values like JSON, the numbers of different types are equal if they have the same value.
+func jsonEqual(a, b interface{}) bool {
+	aBuf, aErr := json.Marshal(a)
+	bBuf, bErr := json.Marshal(b)
+	if aErr != nil || bErr != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return string(aBuf) == string(bBuf)
+}
+
+// CreatePatch
+//
+// Generates a JSON Patch (RFC 6902) which turns a into b, using the changes reported by Diff. Returns nil when there is no difference.
+//
+// Example:
+//
+//   ops := m2obj.CreatePatch(before, after)
+//   buf, _ := json.Marshal(ops) // [{"op":"replace","path":"/db/pool/size","value":20}]
+func CreatePatch(a, b *Object) []PatchOp {
+	var ops []PatchOp
+	for _, c := range Diff(a, b) {
+		op := PatchOp{
+			Path:  toPointer(c.KeyStr),
+			Value: c.New,
+		}
+		switch c.Op {
+		case DiffAdded:
+			op.Op = "add"
+		case DiffRemoved:
+			op.Op = "remove"
+			op.Value = nil
+		default:
+			op.Op = "replace"
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
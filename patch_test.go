@@ -0,0 +1,156 @@
+package m2obj
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObject_ApplyPatch(t *testing.T) {
+	obj := New(Group{
+		"db": Group{
+			"pool": Group{
+				"size": 10,
+			},
+		},
+		"servers":  Array{"s0", "s1"},
+		"a/b":      1,
+		"m~n":      2,
+		"removed":  true,
+		"0":        "group key",
+		"replaced": Array{1},
+	})
+	var events []ChangeEvent
+	_, err := obj.Watch("", func(ev ChangeEvent) {
+		events = append(events, ev)
+	})
+	assert.NoError(t, err)
+	var ops []PatchOp
+	assert.NoError(t, json.Unmarshal([]byte(`[
+		{"op": "test", "path": "/db/pool/size", "value": 10},
+		{"op": "replace", "path": "/db/pool/size", "value": 20},
+		{"op": "add", "path": "/servers/1", "value": "s0.5"},
+		{"op": "add", "path": "/servers/-", "value": {"name": "s2"}},
+		{"op": "remove", "path": "/removed"},
+		{"op": "move", "path": "/servers/0", "from": "/servers/2"},
+		{"op": "copy", "path": "/db/backup", "from": "/db/pool"},
+		{"op": "replace", "path": "/a~1b", "value": "slash"},
+		{"op": "replace", "path": "/m~0n", "value": "tilde"},
+		{"op": "replace", "path": "/0", "value": null},
+		{"op": "replace", "path": "/replaced/0", "value": 2},
+		{"op": "test", "path": "/servers/3/name", "value": "s2"}
+	]`), &ops))
+	assert.NoError(t, obj.ApplyPatch(ops))
+	assert.Equal(t, map[string]interface{}{
+		"db": map[string]interface{}{
+			"pool":   map[string]interface{}{"size": 20.0},
+			"backup": map[string]interface{}{"size": 20.0},
+		},
+		"servers":  []interface{}{"s1", "s0", "s0.5", map[string]interface{}{"name": "s2"}},
+		"a/b":      "slash",
+		"m~n":      "tilde",
+		"0":        nil,
+		"replaced": []interface{}{2.0},
+	}, obj.Staticize())
+	assert.Equal(t, 11, len(events))
+	assert.Equal(t, ChangeEvent{KeyStr: "servers.[1]", Op: OpInsert, New: "s0.5"}, events[1])
+	assert.Equal(t, obj.MustGet("servers"), obj.MustGet("servers.[3]").Parent())
+
+	// replace the whole object
+	assert.NoError(t, obj.ApplyPatch([]PatchOp{
+		{Op: "replace", Path: "", Value: New(Group{"x": 1})},
+	}))
+	assert.Equal(t, map[string]interface{}{"x": 1}, obj.Staticize())
+}
+
+func TestObject_ApplyPatchAtomic(t *testing.T) {
+	obj := New(Group{
+		"a":   1,
+		"arr": Array{1, 2},
+	})
+	count := 0
+	_, err := obj.Watch("", func(ev ChangeEvent) {
+		count++
+	})
+	assert.NoError(t, err)
+	type TestData struct {
+		op      PatchOp
+		wantErr error
+	}
+	testData := []TestData{
+		{PatchOp{Op: "test", Path: "/a", Value: 2}, ErrTestFailed},
+		{PatchOp{Op: "remove", Path: "/b"}, ErrKeyNotFound},
+		{PatchOp{Op: "replace", Path: "/b", Value: 1}, ErrKeyNotFound},
+		{PatchOp{Op: "add", Path: "/b/c", Value: 1}, ErrKeyNotFound},
+		{PatchOp{Op: "add", Path: "/arr/4", Value: 1}, ErrIndexOverflow},
+		{PatchOp{Op: "add", Path: "/arr/01", Value: 1}, ErrInvalidKeyStr},
+		{PatchOp{Op: "add", Path: "a", Value: 1}, ErrInvalidKeyStr},
+		{PatchOp{Op: "remove", Path: "/arr/-"}, ErrIndexOverflow},
+		{PatchOp{Op: "move", Path: "/arr/0", From: "/arr"}, ErrInvalidPatch},
+		{PatchOp{Op: "copy", Path: "/c", From: "/b"}, ErrKeyNotFound},
+		{PatchOp{Op: "unknown", Path: "/a"}, ErrInvalidPatch},
+	}
+	for _, data := range testData {
+		err := obj.ApplyPatch([]PatchOp{
+			{Op: "replace", Path: "/a", Value: 100},
+			{Op: "add", Path: "/arr/0", Value: 0},
+			data.op,
+		})
+		assert.True(t, errors.Is(err, data.wantErr), "%v: %v", data.op, err)
+		var patchErr *PatchError
+		if assert.True(t, errors.As(err, &patchErr)) {
+			assert.Equal(t, 2, patchErr.Index)
+			assert.Equal(t, data.op, patchErr.Op)
+		}
+	}
+	assert.Equal(t, map[string]interface{}{
+		"a":   1,
+		"arr": []interface{}{1, 2},
+	}, obj.Staticize())
+	assert.Equal(t, 0, count)
+}
+
+func TestCreatePatch(t *testing.T) {
+	a := New(Group{
+		"db": Group{
+			"pool": Group{
+				"size": 10,
+			},
+		},
+		"servers": Array{"s0", "s1", "s2", "s3"},
+		"a/b":     1,
+		"removed": true,
+	})
+	b := New(Group{
+		"db": Group{
+			"pool": Group{
+				"size": 20,
+			},
+		},
+		"servers": Array{"s0"},
+		"a/b":     Group{"c": 1},
+		"~":       "added",
+	})
+	ops := CreatePatch(a, b)
+	assert.Equal(t, []PatchOp{
+		{Op: "replace", Path: "/a~1b", Value: map[string]interface{}{"c": 1}},
+		{Op: "replace", Path: "/db/pool/size", Value: 20},
+		{Op: "remove", Path: "/removed"},
+		{Op: "remove", Path: "/servers/3"},
+		{Op: "remove", Path: "/servers/2"},
+		{Op: "remove", Path: "/servers/1"},
+		{Op: "add", Path: "/~0", Value: "added"},
+	}, ops)
+	buf, err := json.Marshal(ops[:3])
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"op": "replace", "path": "/a~1b", "value": {"c": 1}},
+		{"op": "replace", "path": "/db/pool/size", "value": 20},
+		{"op": "remove", "path": "/removed"}
+	]`, string(buf))
+	assert.NoError(t, a.ApplyPatch(ops))
+	assert.Nil(t, Diff(a, b))
+	assert.Nil(t, CreatePatch(a, b))
+}
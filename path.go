@@ -0,0 +1,107 @@
+package m2obj
+
+import "sync"
+
+// Path
+//
+// A parsed keyStr. Parsing a keyStr once and using the Path many times avoids splitting and checking the keyStr on every access.
+//
+// A Path is immutable, so it is safe to share between goroutines.
+//
+// Example:
+//
+//   var portPath = m2obj.MustParsePath("server.hosts.[0].port")
+//
+//   port := obj.MustGetPath(portPath).ValInt()
+type Path struct {
+	keyStr string
+	keys   []keyItem
+}
+
+// ParsePath
+//
+// Parse a keyStr to a Path. A *PathError wrapping ErrInvalidKeyStr will be reported when the keyStr doesn't meet the agreed format.
+func ParsePath(keyStr string) (p Path, err error) {
+	defer func() {
+		if pan := recover(); pan != nil {
+			err = &PathError{
+				KeyStr: keyStr,
+				Index:  -1,
+				Err:    errorOf(pan),
+			}
+		}
+	}()
+	return Path{
+		keyStr: keyStr,
+		keys:   split(keyStr),
+	}, nil
+}
+
+// MustParsePath
+//
+// Like ParsePath, but panic when error occurred
+func MustParsePath(keyStr string) Path {
+	p, err := ParsePath(keyStr)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// String
+//
+// Returns the original keyStr of the Path
+func (p Path) String() string {
+	return p.keyStr
+}
+
+// Len
+//
+// Returns the count of keys in the Path
+func (p Path) Len() int {
+	return len(p.keys)
+}
+
+// parent
+//
+// Returns the Path without the last key. The keyStr of the returned Path is kept for error reports.
+func (p Path) parent() Path {
+	return Path{
+		keyStr: p.keyStr,
+		keys:   p.keys[:len(p.keys)-1],
+	}
+}
+
+// pathCacheSize is the max count of keyStr cached by parseKeyStr
+const pathCacheSize = 4096
+
+// pathCache caches the Path parsed from keyStr, it will be cleared when it is full.
+var pathCache = struct {
+	sync.RWMutex
+	m map[string]Path
+}{
+	m: make(map[string]Path),
+}
+
+// parseKeyStr
+//
+// Likes ParsePath, but using the pathCache.
+func parseKeyStr(keyStr string) (p Path, err error) {
+	var ok bool
+	pathCache.RLock()
+	p, ok = pathCache.m[keyStr]
+	pathCache.RUnlock()
+	if ok {
+		return
+	}
+	if p, err = ParsePath(keyStr); err != nil {
+		return
+	}
+	pathCache.Lock()
+	if len(pathCache.m) >= pathCacheSize {
+		pathCache.m = make(map[string]Path)
+	}
+	pathCache.m[keyStr] = p
+	pathCache.Unlock()
+	return
+}
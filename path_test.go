@@ -0,0 +1,151 @@
+package m2obj
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePath(t *testing.T) {
+	p, err := ParsePath(`a."b.c".[1]`)
+	assert.NoError(t, err)
+	assert.Equal(t, `a."b.c".[1]`, p.String())
+	assert.Equal(t, 3, p.Len())
+	_, err = ParsePath(`a."b`)
+	assert.Error(t, err)
+	assert.Panics(t, func() {
+		MustParsePath(`a."b`)
+	})
+}
+
+func TestObject_Path(t *testing.T) {
+	obj := New(Group{
+		"a": Group{
+			"b.c": Array{1, 2, 3},
+		},
+	})
+	p := MustParsePath(`a."b.c".[1]`)
+	assert.Equal(t, 2, obj.MustGetPath(p).ValInt())
+	assert.True(t, obj.HasPath(p))
+	assert.NoError(t, obj.SetPath(p, 20))
+	assert.Equal(t, 20, obj.MustGet(`a."b.c".[1]`).ValInt())
+	assert.Error(t, obj.SetPath(MustParsePath(`a."b.c".[-4]`), 0))
+	assert.False(t, obj.HasPath(MustParsePath("a.d")))
+	assert.True(t, obj.RemovePath(MustParsePath(`a."b.c"`)))
+	assert.False(t, obj.HasPath(p))
+	assert.Panics(t, func() {
+		obj.MustGetPath(p)
+	})
+	// the same Path on different objects
+	p2 := MustParsePath("x.y")
+	obj1, obj2 := New(Group{}), New(Group{})
+	assert.NoError(t, obj1.SetPath(p2, 1))
+	assert.NoError(t, obj2.SetPath(p2, 2))
+	assert.Equal(t, 1, obj1.MustGetPath(p2).ValInt())
+	assert.Equal(t, 2, obj2.MustGetPath(p2).ValInt())
+}
+
+func TestParseKeyStr(t *testing.T) {
+	p1, err := parseKeyStr("a.b.[0]")
+	assert.NoError(t, err)
+	p2, err := parseKeyStr("a.b.[0]")
+	assert.NoError(t, err)
+	assert.Equal(t, p1, p2)
+	_, err = parseKeyStr(`"`)
+	assert.Error(t, err)
+	for i := 0; i < pathCacheSize*2; i++ {
+		_, _ = parseKeyStr(strconv.Itoa(i))
+	}
+	assert.LessOrEqual(t, len(pathCache.m), pathCacheSize)
+}
+
+// legacySplitAndDig is the digging with strings.Split and regexp before Path, kept here for comparison in benchmarks.
+func legacySplitAndDig(current *Object, keyStr string) *Object {
+	tObj := current
+	for _, key := range strings.Split(strings.TrimSpace(keyStr), ".") {
+		if key == "" {
+			continue
+		}
+		switch tObj.val.(type) {
+		case *groupData:
+			tObj = (*tObj.val.(*groupData))[key]
+		case *arrayData:
+			reg := regexp.MustCompile(`\[(\d+)]`)
+			index, _ := strconv.Atoi(reg.FindStringSubmatch(key)[1])
+			tObj = (*tObj.val.(*arrayData))[index]
+		}
+	}
+	return tObj
+}
+
+func newBenchObject() *Object {
+	return New(Group{
+		"a": Group{
+			"b": Array{
+				0, 1, 2,
+				Group{
+					"c": Array{
+						Group{
+							"d": "here",
+						},
+					},
+				},
+			},
+		},
+	})
+}
+
+const benchKeyStr = "a.b.[3].c.[0].d"
+
+func BenchmarkGet_Legacy(b *testing.B) {
+	obj := newBenchObject()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = legacySplitAndDig(obj, benchKeyStr)
+	}
+}
+
+func BenchmarkGet_Uncached(b *testing.B) {
+	obj := newBenchObject()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = obj.GetPath(MustParsePath(benchKeyStr))
+	}
+}
+
+func BenchmarkGet_KeyStr(b *testing.B) {
+	obj := newBenchObject()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = obj.Get(benchKeyStr)
+	}
+}
+
+func BenchmarkGet_Path(b *testing.B) {
+	obj := newBenchObject()
+	p := MustParsePath(benchKeyStr)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = obj.GetPath(p)
+	}
+}
+
+func BenchmarkSet_KeyStr(b *testing.B) {
+	obj := newBenchObject()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = obj.Set(benchKeyStr, i)
+	}
+}
+
+func BenchmarkSet_Path(b *testing.B) {
+	obj := newBenchObject()
+	p := MustParsePath(benchKeyStr)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = obj.SetPath(p, i)
+	}
+}
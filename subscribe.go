@@ -0,0 +1,142 @@
+package m2obj
+
+import (
+	"context"
+	"sync"
+)
+
+// OverflowPolicy
+//
+// Decides what to do when the channel of a subscription is full, see Subscribe.
+type OverflowPolicy int
+
+const (
+	OverflowBlock      OverflowPolicy = iota // DEFAULT. Wait until the event is received or the context is done, the goroutine making the change waits as well
+	OverflowDropNewest                       // Drop the new event
+	OverflowDropOldest                       // Drop the oldest event in the channel to make room for the new one, works like OverflowDropNewest for an unbuffered channel
+)
+
+// DefaultSubscribeBufferSize is the buffer size of the channel returned by Subscribe, when WithBufferSize is not given.
+const DefaultSubscribeBufferSize = 64
+
+// SubscribeOption
+//
+// The option of Subscribe, such as WithBufferSize and WithOverflowPolicy.
+type SubscribeOption func(s *subscription)
+
+// WithBufferSize
+//
+// Sets the buffer size of the channel returned by Subscribe, 0 for an unbuffered channel.
+func WithBufferSize(size int) SubscribeOption {
+	return func(s *subscription) {
+		if size < 0 {
+			size = 0
+		}
+		s.bufferSize = size
+	}
+}
+
+// WithOverflowPolicy
+//
+// Sets what to do when the channel returned by Subscribe is full.
+func WithOverflowPolicy(policy OverflowPolicy) SubscribeOption {
+	return func(s *subscription) {
+		s.policy = policy
+	}
+}
+
+// subscription
+//
+// The state of a channel returned by Subscribe.
+type subscription struct {
+	ctx        context.Context
+	bufferSize int
+	policy     OverflowPolicy
+	ch         chan ChangeEvent
+	// protects the closing of ch from the sending
+	mutex  sync.RWMutex
+	closed bool
+}
+
+// Subscribe
+//
+// Like Watch, but sends the ChangeEvents to the returned channel instead of calling a listener,
+// so that the changes can be received (or selected) in other goroutines.
+//
+// The channel is buffered by DefaultSubscribeBufferSize, and the goroutine making a change waits when the channel is full (the OverflowBlock policy).
+// Use WithBufferSize and WithOverflowPolicy to change them. Events are never sent after the context is done.
+//
+// The subscription is cancelled and the channel is closed when the context is done.
+//
+// Panic if the pattern is not a valid keyStr for Watch.
+//
+// Example:
+//
+//   ctx, cancel := context.WithCancel(context.Background())
+//   defer cancel()
+//   events := obj.Subscribe(ctx, "feature.*", m2obj.WithOverflowPolicy(m2obj.OverflowDropOldest))
+//   for ev := range events {
+//     fmt.Println(ev.KeyStr, "->", ev.New)
+//   }
+func (o *Object) Subscribe(ctx context.Context, pattern string, opts ...SubscribeOption) <-chan ChangeEvent {
+	s := &subscription{
+		ctx:        ctx,
+		bufferSize: DefaultSubscribeBufferSize,
+		policy:     OverflowBlock,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.ch = make(chan ChangeEvent, s.bufferSize)
+	unwatch, err := o.Watch(pattern, s.send)
+	if err != nil {
+		panic(err)
+	}
+	go func() {
+		<-ctx.Done()
+		unwatch()
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.closed = true
+		close(s.ch)
+	}()
+	return s.ch
+}
+
+// send
+//
+// The listener of a subscription, sends the event by the OverflowPolicy.
+func (s *subscription) send(ev ChangeEvent) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if s.closed || s.ctx.Err() != nil {
+		return
+	}
+	switch s.policy {
+	case OverflowDropNewest:
+		select {
+		case s.ch <- ev:
+		default:
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case s.ch <- ev:
+				return
+			default:
+			}
+			if cap(s.ch) == 0 {
+				return
+			}
+			select {
+			case <-s.ch:
+			default:
+			}
+		}
+	default: // OverflowBlock
+		select {
+		case s.ch <- ev:
+		case <-s.ctx.Done():
+		}
+	}
+}
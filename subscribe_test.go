@@ -0,0 +1,89 @@
+package m2obj
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObject_Subscribe(t *testing.T) {
+	obj := New(Group{
+		"feature": Group{
+			"a": false,
+		},
+		"other": 1,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	events := obj.Subscribe(ctx, "feature.*")
+	done := make(chan []ChangeEvent)
+	go func() {
+		var received []ChangeEvent
+		for ev := range events {
+			received = append(received, ev)
+		}
+		done <- received
+	}()
+	assert.NoError(t, obj.Set("feature.a", true))
+	assert.NoError(t, obj.Set("other", 2))
+	assert.NoError(t, obj.Set("feature.b.c", "x"))
+	assert.True(t, obj.Remove("feature.a"))
+	cancel()
+	select {
+	case received := <-done:
+		assert.Equal(t, []ChangeEvent{
+			{KeyStr: "feature.a", Op: OpSet, Old: false, New: true},
+			{KeyStr: "feature.b.c", Op: OpSet, New: "x"},
+			{KeyStr: "feature.a", Op: OpRemove, Old: true},
+		}, received)
+	case <-time.After(time.Second):
+		t.Fatal("the channel is not closed after cancelling")
+	}
+	// no more events after cancelling
+	assert.NoError(t, obj.Set("feature.a", false))
+
+	// invalid pattern
+	assert.Panics(t, func() {
+		obj.Subscribe(context.Background(), "feature.[+]")
+	})
+}
+
+func TestObject_SubscribeOverflow(t *testing.T) {
+	obj := New(Group{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	newest := obj.Subscribe(ctx, "", WithBufferSize(2), WithOverflowPolicy(OverflowDropNewest))
+	oldest := obj.Subscribe(ctx, "", WithBufferSize(2), WithOverflowPolicy(OverflowDropOldest))
+	unbuffered := obj.Subscribe(ctx, "", WithBufferSize(0), WithOverflowPolicy(OverflowDropOldest))
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, obj.Set("a", i))
+	}
+	assert.Equal(t, 0, (<-newest).New)
+	assert.Equal(t, 2, (<-oldest).New)
+	select {
+	case <-unbuffered:
+		t.Fatal("an event is sent to an unbuffered channel without receivers")
+	default:
+	}
+
+	// a blocked change is released when the context is done
+	blockCtx, blockCancel := context.WithCancel(context.Background())
+	blocked := obj.Subscribe(blockCtx, "", WithBufferSize(0))
+	go func() {
+		_ = obj.Set("b", 1)
+	}()
+	assert.Equal(t, 1, (<-blocked).New)
+	setDone := make(chan struct{})
+	go func() {
+		_ = obj.Set("b", 2)
+		close(setDone)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	blockCancel()
+	select {
+	case <-setDone:
+	case <-time.After(time.Second):
+		t.Fatal("the change is still blocked after cancelling")
+	}
+}
@@ -7,3 +7,31 @@ type Formatter interface {
 	Marshal(obj *Object) (data []byte, err error)
 	Unmarshal(data []byte) (obj *Object, err error)
 }
+
+// ObjectType
+//
+// The type of an Object, see Object.Type
+type ObjectType int
+
+const (
+	UnknownType ObjectType = iota
+	NilType
+	ValueType
+	GroupType
+	ArrayType
+)
+
+func (t ObjectType) String() string {
+	switch t {
+	case NilType:
+		return "Nil"
+	case ValueType:
+		return "Value"
+	case GroupType:
+		return "Group"
+	case ArrayType:
+		return "Array"
+	default:
+		return "Unknown"
+	}
+}
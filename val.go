@@ -1,11 +1,43 @@
 package m2obj
 
-import "reflect"
+import (
+	"net"
+	"net/url"
+	"reflect"
+	"time"
+)
+
+// Type
+//
+// Returns the ObjectType of this Object, which is one of NilType, ValueType, GroupType and ArrayType.
+func (o *Object) Type() ObjectType {
+	defer o.rLock()()
+	return o.objType()
+}
+
+func (o *Object) objType() ObjectType {
+	if o == nil || o.val == nil {
+		return NilType
+	}
+	switch o.val.(type) {
+	case *groupData:
+		return GroupType
+	case *arrayData:
+		return ArrayType
+	default:
+		return ValueType
+	}
+}
 
 // IsGroup
 //
 // If this Object is maintaining a(n) Group, return true, or else return false.
 func (o *Object) IsGroup() bool {
+	defer o.rLock()()
+	return o.isGroup()
+}
+
+func (o *Object) isGroup() bool {
 	if o.val == nil {
 		return false
 	}
@@ -21,6 +53,11 @@ func (o *Object) IsGroup() bool {
 //
 // If this Object is maintaining a(n) Array, return true, or else return false.
 func (o *Object) IsArray() bool {
+	defer o.rLock()()
+	return o.isArray()
+}
+
+func (o *Object) isArray() bool {
 	if o.val == nil {
 		return false
 	}
@@ -36,16 +73,18 @@ func (o *Object) IsArray() bool {
 //
 // If this Object is maintaining a(n) value neither Group nor Array, return true, or else return false.
 func (o *Object) IsValue() bool {
+	defer o.rLock()()
 	if o.val == nil {
 		return false
 	}
-	return !o.IsGroup() && !o.IsArray()
+	return !o.isGroup() && !o.isArray()
 }
 
 // IsNil
 //
 // If this Object is not maintaining anything, return true, or else return false.
 func (o *Object) IsNil() bool {
+	defer o.rLock()()
 	return o.val == nil
 }
 
@@ -53,6 +92,7 @@ func (o *Object) IsNil() bool {
 //
 // If this Object is maintaining anything with type ty, return true, or else return false.
 func (o *Object) Is(ty reflect.Type) bool {
+	defer o.rLock()()
 	return reflect.TypeOf(o.val) == ty
 }
 
@@ -61,6 +101,7 @@ func (o *Object) Is(ty reflect.Type) bool {
 // If this Object is maintaining anything with the same type of v, return true, or else return false.
 func (o *Object) IsLike(v interface{}) bool {
 	tv := getDeepestValue(v)
+	defer o.rLock()()
 	return reflect.TypeOf(o.val) == reflect.TypeOf(tv)
 }
 
@@ -68,159 +109,367 @@ func (o *Object) IsLike(v interface{}) bool {
 //
 // Change the Object's val, no type stipulation to value, like New
 func (o *Object) SetVal(value interface{}) {
-	o.val = getDeepestValue(value)
-	o.buildParentLink(o.Parent())
-	o.callOnChange()
+	value = detachValue(value)
+	o.mustWrite(func() {
+		c := o.beginChange(nil, OpSet, o)
+		o.setVal(value)
+		c.end(o)
+	})
 }
 
-func (o *Object) setVal(value interface{}, needCallOnChange bool) {
+// setVal
+//
+// SetVal without locking and change reporting
+func (o *Object) setVal(value interface{}) {
 	o.val = getDeepestValue(value)
-	if needCallOnChange {
-		o.callOnChange()
-	}
+	o.buildParentLink(o.parent)
 }
 
 // Val
 //
 // Get the inner value of an Object
 func (o *Object) Val() interface{} {
+	defer o.rLock()()
 	return o.val
 }
 
 // ValStr
 //
-// Get the inner value of an Object, and assert it is or transform it to a `string`.
+// Get the inner value of an Object, and assert it is or transform it to a `string`. Numbers are formatted as decimal strings.
 func (o *Object) ValStr() string {
-	v := reflect.ValueOf(o.val)
-	v = v.Convert(reflect.TypeOf(""))
-	return v.String()
+	defer o.rLock()()
+	v, err := toString(o.val)
+	if err != nil {
+		panic(err)
+	}
+	return v
 }
 
 // ValBool
 //
-// Get the inner value of an Object, and assert it is or transform it to a `bool`.
+// Get the inner value of an Object, and assert it is or transform it to a `bool`. Strings like "true"/"yes"/"on"/"1" and "false"/"no"/"off"/"0" are accepted.
 func (o *Object) ValBool() bool {
-	v := reflect.ValueOf(o.val)
-	v = v.Convert(reflect.TypeOf(true))
-	return v.Bool()
+	defer o.rLock()()
+	v, err := toBool(o.val)
+	if err != nil {
+		panic(err)
+	}
+	return v
 }
 
 // ValByte
 //
 // Get the inner value of an Object, and assert it is or transform it to a `byte`.
 func (o *Object) ValByte() byte {
-	v := reflect.ValueOf(o.val)
-	v = v.Convert(reflect.TypeOf(byte(1)))
-	return v.Interface().(byte)
+	defer o.rLock()()
+	v, err := toUint(o.val, reflect.Uint8, o.isStrict())
+	if err != nil {
+		panic(err)
+	}
+	return byte(v)
 }
 
 // ValBytes
 //
 // Get the inner value of an Object, and assert it is or transform it to a `[]byte`.
 func (o *Object) ValBytes() []byte {
-	v := reflect.ValueOf(o.val)
-	// 针对rune转[]byte做特殊处理
-	if v.Type() == reflect.TypeOf(' ') {
-		return New(o.ValStr()).ValBytes()
-	}
-	// 针对[]rune转[]byte做特殊处理
-	if v.Type() == reflect.TypeOf([]rune{}) {
-		return New(o.ValStr()).ValBytes()
+	defer o.rLock()()
+	v, err := toBytes(o.val)
+	if err != nil {
+		panic(err)
 	}
-	v = v.Convert(reflect.TypeOf([]byte{}))
-	return v.Bytes()
+	return v
 }
 
 // ValRune
 //
-// Get the inner value of an Object, and assert it is or transform it to an `rune`.
+// Get the inner value of an Object, and assert it is or transform it to an `rune`. A string with only one character is transformed to the character.
 func (o *Object) ValRune() rune {
-	v := reflect.ValueOf(o.val)
-	v = v.Convert(reflect.TypeOf(' '))
-	return v.Interface().(rune)
+	defer o.rLock()()
+	v, err := toRune(o.val, o.isStrict())
+	if err != nil {
+		panic(err)
+	}
+	return v
 }
 
 // ValRunes
 //
 // Get the inner value of an Object, and assert it is or transform it to an `[]rune`.
 func (o *Object) ValRunes() []rune {
-	v := reflect.ValueOf(o.val)
-	// 针对[]byte转[]rune做特殊处理
-	if v.Type() == reflect.TypeOf([]byte{}) {
-		return New(o.ValStr()).ValRunes()
+	defer o.rLock()()
+	v, err := toRunes(o.val)
+	if err != nil {
+		panic(err)
 	}
-	v = v.Convert(reflect.TypeOf([]rune{}))
-	return v.Interface().([]rune)
+	return v
 }
 
 // ValInt
 //
-// Get the inner value of an Object, and assert it is or transform it to an `int`.
+// Get the inner value of an Object, and assert it is or transform it to an `int`. Numeric strings are parsed.
 func (o *Object) ValInt() int {
-	v := reflect.ValueOf(o.val)
-	v = v.Convert(reflect.TypeOf(1))
-	return v.Interface().(int)
+	defer o.rLock()()
+	v, err := toInt(o.val, reflect.Int, o.isStrict())
+	if err != nil {
+		panic(err)
+	}
+	return int(v)
 }
 
 // ValInt8
 //
-// Get the inner value of an Object, and assert it is or transform it to an `int8`.
+// Get the inner value of an Object, and assert it is or transform it to an `int8`. Numeric strings are parsed.
 func (o *Object) ValInt8() int8 {
-	v := reflect.ValueOf(o.val)
-	v = v.Convert(reflect.TypeOf(int8(1)))
-	return v.Interface().(int8)
+	defer o.rLock()()
+	v, err := toInt(o.val, reflect.Int8, o.isStrict())
+	if err != nil {
+		panic(err)
+	}
+	return int8(v)
 }
 
 // ValInt16
 //
-// Get the inner value of an Object, and assert it is or transform it to an `int16`.
+// Get the inner value of an Object, and assert it is or transform it to an `int16`. Numeric strings are parsed.
 func (o *Object) ValInt16() int16 {
-	v := reflect.ValueOf(o.val)
-	v = v.Convert(reflect.TypeOf(int16(1)))
-	return v.Interface().(int16)
+	defer o.rLock()()
+	v, err := toInt(o.val, reflect.Int16, o.isStrict())
+	if err != nil {
+		panic(err)
+	}
+	return int16(v)
 }
 
 // ValInt32
 //
-// Get the inner value of an Object, and assert it is or transform it to an `int32`.
+// Get the inner value of an Object, and assert it is or transform it to an `int32`. Numeric strings are parsed.
 func (o *Object) ValInt32() int32 {
-	v := reflect.ValueOf(o.val)
-	v = v.Convert(reflect.TypeOf(int32(1)))
-	return v.Interface().(int32)
+	defer o.rLock()()
+	v, err := toInt(o.val, reflect.Int32, o.isStrict())
+	if err != nil {
+		panic(err)
+	}
+	return int32(v)
 }
 
 // ValInt64
 //
-// Get the inner value of an Object, and assert it is or transform it to an `int64`.
+// Get the inner value of an Object, and assert it is or transform it to an `int64`. Numeric strings are parsed.
 func (o *Object) ValInt64() int64 {
-	v := reflect.ValueOf(o.val)
-	v = v.Convert(reflect.TypeOf(int64(1)))
-	return v.Int()
+	defer o.rLock()()
+	v, err := toInt(o.val, reflect.Int64, o.isStrict())
+	if err != nil {
+		panic(err)
+	}
+	return int64(v)
 }
 
 // ValUint
 //
-// Get the inner value of an Object, and assert it is or transform it to an `uint64`.
+// Get the inner value of an Object, and assert it is or transform it to an `uint64`. Numeric strings are parsed.
 func (o *Object) ValUint() uint64 {
-	v := reflect.ValueOf(o.val)
-	v = v.Convert(reflect.TypeOf(uint64(1)))
-	return v.Uint()
+	defer o.rLock()()
+	v, err := toUint(o.val, reflect.Uint64, o.isStrict())
+	if err != nil {
+		panic(err)
+	}
+	return v
 }
 
 // ValFloat32
 //
-// Get the inner value of an Object, and assert it is or transform it to a `float32`.
+// Get the inner value of an Object, and assert it is or transform it to a `float32`. Numeric strings are parsed.
 func (o *Object) ValFloat32() float32 {
-	v := reflect.ValueOf(o.val)
-	v = v.Convert(reflect.TypeOf(float32(1)))
-	return v.Interface().(float32)
+	defer o.rLock()()
+	v, err := toFloat(o.val, reflect.Float32, o.isStrict())
+	if err != nil {
+		panic(err)
+	}
+	return float32(v)
 }
 
 // ValFloat64
 //
-// Get the inner value of an Object, and assert it is or transform it to a `float64`.
+// Get the inner value of an Object, and assert it is or transform it to a `float64`. Numeric strings are parsed.
 func (o *Object) ValFloat64() float64 {
-	v := reflect.ValueOf(o.val)
-	v = v.Convert(reflect.TypeOf(float64(1)))
-	return v.Float()
+	defer o.rLock()()
+	v, err := toFloat(o.val, reflect.Float64, o.isStrict())
+	if err != nil {
+		panic(err)
+	}
+	return float64(v)
+}
+
+// ValDuration
+//
+// Get the inner value of an Object, and assert it is or transform it to a `time.Duration`. A string like "30s" is parsed by time.ParseDuration, and a number is regarded as nanoseconds.
+func (o *Object) ValDuration() time.Duration {
+	defer o.rLock()()
+	v, err := toDuration(o.val, o.isStrict())
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// ValTime
+//
+// Get the inner value of an Object, and assert it is or transform it to a `time.Time`. A string is parsed by the layouts in order, or by time.RFC3339 when no layout is given. A number is regarded as a Unix timestamp in seconds.
+func (o *Object) ValTime(layouts ...string) time.Time {
+	defer o.rLock()()
+	v, err := toTime(o.val, layouts, o.isStrict())
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// ValURL
+//
+// Get the inner value of an Object, and assert it is or transform it to a `*url.URL`.
+func (o *Object) ValURL() *url.URL {
+	defer o.rLock()()
+	v, err := toURL(o.val)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// ValIP
+//
+// Get the inner value of an Object, and assert it is or transform it to a `net.IP`.
+func (o *Object) ValIP() net.IP {
+	defer o.rLock()()
+	v, err := toIP(o.val)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// ValByteSize
+//
+// Get the inner value of an Object, and assert it is or transform it to a count of bytes as `uint64`.
+// A string like "512MiB", "1.5GB" or "10k" is parsed, the units with `i` (KiB, MiB, etc.) and the single letters (K, M, etc.) are powers of 1024, and the others (KB, MB, etc.) are powers of 1000.
+func (o *Object) ValByteSize() uint64 {
+	defer o.rLock()()
+	v, err := toByteSize(o.val, o.isStrict())
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// ValStringSlice
+//
+// Get the inner value of an Object as a `[]string`. Each element of an Array Object is transformed like ValStr, and a string value is split by commas.
+func (o *Object) ValStringSlice() []string {
+	defer o.rLock()()
+	vals, err := o.listVals("[]string")
+	if err != nil {
+		panic(err)
+	}
+	slice := make([]string, len(vals))
+	for i, val := range vals {
+		if slice[i], err = toString(val); err != nil {
+			panic(newListConvertErr(o.val, "[]string", i, err))
+		}
+	}
+	return slice
+}
+
+// ValIntSlice
+//
+// Get the inner value of an Object as a `[]int`. Each element of an Array Object is transformed like ValInt, and a string value is split by commas.
+func (o *Object) ValIntSlice() []int {
+	defer o.rLock()()
+	vals, err := o.listVals("[]int")
+	if err != nil {
+		panic(err)
+	}
+	slice := make([]int, len(vals))
+	for i, val := range vals {
+		v, err := toInt(val, reflect.Int, o.isStrict())
+		if err != nil {
+			panic(newListConvertErr(o.val, "[]int", i, err))
+		}
+		slice[i] = int(v)
+	}
+	return slice
+}
+
+// ValStringMap
+//
+// Get the inner value of a Group Object as a `map[string]string`. Each child of the Group is transformed like ValStr.
+func (o *Object) ValStringMap() map[string]string {
+	defer o.rLock()()
+	grp, ok := o.val.(*groupData)
+	if !ok {
+		panic(newConvertErr(o.val, "map[string]string", ""))
+	}
+	m := make(map[string]string, len(*grp))
+	for k, obj := range *grp {
+		v, err := toString(obj.valOrNil())
+		if err != nil {
+			panic(newConvertErr(o.val, "map[string]string", "the child {"+k+"}: "+err.Error()))
+		}
+		m[k] = v
+	}
+	return m
+}
+
+// listVals
+//
+// Returns the values of the elements of an Array Object, or the items of a comma-separated string value, without locking.
+func (o *Object) listVals(to string) ([]interface{}, error) {
+	switch tv := o.val.(type) {
+	case *arrayData:
+		vals := make([]interface{}, len(*tv))
+		for i, obj := range *tv {
+			vals[i] = obj.valOrNil()
+		}
+		return vals, nil
+	case string:
+		items := splitList(tv)
+		vals := make([]interface{}, len(items))
+		for i, item := range items {
+			vals[i] = item
+		}
+		return vals, nil
+	default:
+		return nil, newConvertErr(o.val, to, "")
+	}
+}
+
+// SetStrict
+//
+// Enable or disable the strict mode of the conversions in ValXxx (and GetXxx) for the whole object tree.
+// In the strict mode, the lossy conversions are rejected, such as a float with fraction or an overflowing number to an integer.
+func (o *Object) SetStrict(strict bool) {
+	defer o.lock()()
+	o.getTree().strict = strict
+}
+
+// IsStrict
+//
+// Returns if the object tree is in the strict mode, see SetStrict.
+func (o *Object) IsStrict() bool {
+	defer o.rLock()()
+	return o.isStrict()
+}
+
+func (o *Object) isStrict() bool {
+	return o.getTree().strict
+}
+
+// valOrNil
+//
+// Likes Val, but without locking, and returns nil for a nil object.
+func (o *Object) valOrNil() interface{} {
+	if o == nil {
+		return nil
+	}
+	return o.val
 }
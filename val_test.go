@@ -136,9 +136,12 @@ func TestObject_Vals3(t *testing.T) {
 	assert.Equal(t, []byte("😘哟哟切克闹"), obj.ValBytes())
 	assert.Equal(t, []rune("😘哟哟切克闹"), obj.ValRunes())
 	// ---- rune ----
+	// a rune is an int32 number, so it is formatted as a decimal string
 	obj2 := New('❤')
-	assert.Equal(t, []byte(string('❤')), obj2.ValBytes())
-	assert.Equal(t, "❤", obj2.ValStr())
+	assert.Equal(t, []byte("10084"), obj2.ValBytes())
+	assert.Equal(t, "10084", obj2.ValStr())
+	assert.Equal(t, '❤', obj2.ValRune())
+	assert.Equal(t, '❤', New("❤").ValRune())
 }
 
 func TestObject_Is(t *testing.T) {
@@ -0,0 +1,277 @@
+package m2obj
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ChangeOp
+//
+// The operation of a change, see ChangeEvent.
+type ChangeOp int
+
+const (
+	OpSet    ChangeOp = iota + 1 // the object is set, replaced or merged
+	OpRemove                     // the object is removed from its Group or Array
+	OpInsert                     // the object is inserted (or pushed) into an Array
+)
+
+func (op ChangeOp) String() string {
+	switch op {
+	case OpSet:
+		return "set"
+	case OpRemove:
+		return "remove"
+	case OpInsert:
+		return "insert"
+	default:
+		return "unknown"
+	}
+}
+
+// ChangeEvent
+//
+// Describes a change of the object tree, which is passed to the listeners registered by Watch.
+type ChangeEvent struct {
+	// the full keyStr of the changed object from the root of the object tree, "" for the root itself
+	KeyStr string
+	// the operation of the change
+	Op ChangeOp
+	// the staticized values (see Staticize) before and after the change, nil when the object doesn't exist
+	Old interface{}
+	New interface{}
+	// who makes the change, set by the internal writings (e.g. a FileSyncer loading) to skip their own changes
+	source interface{}
+}
+
+// watcher
+//
+// A listener registered by Watch, the keys are relative to the object it is registered on.
+type watcher struct {
+	keys []keyItem
+	fn   func(ev ChangeEvent)
+}
+
+// match
+//
+// Returns if a change of the object located by the keys should be reported to the watcher,
+// which means the changed object is at, under or above the watched one. A wildcard key `*` matches any key.
+func (w *watcher) match(keys []keyItem) bool {
+	for i := 0; i < len(w.keys) && i < len(keys); i++ {
+		a, b := w.keys[i], keys[i]
+		if a.isWildcard() {
+			continue
+		}
+		if a.isIndex != b.isIndex || a.isIndex && a.index != b.index || !a.isIndex && a.key != b.key {
+			return false
+		}
+	}
+	return true
+}
+
+// isWildcard
+//
+// Returns if the key is an unquoted `*`, which matches any key of a Group or an Array when watching.
+func (item keyItem) isWildcard() bool {
+	return !item.literal && item.key == "*"
+}
+
+// Watch
+//
+// Registers the listener fn for the changes at or under the child located by the keyStr, and returns the func to unregister it.
+//
+// The child doesn't need to exist when watching. The changes replacing or removing it as a whole are reported as well,
+// such as `obj.Set("db", ...)` when watching "db.pool". An empty keyStr watches all of the changes under the object.
+//
+// A key `*` in the keyStr matches any key, such as "servers.*.port". Quote or escape it to watch a key named "*".
+//
+// The listeners are called synchronously in the goroutine making the change, after the object tree is unlocked, so they can access the tree freely.
+// Only the changes in the same object tree are reported, a listener never knows the changes after the watching object is removed from the tree.
+//
+// Multiple listeners can be registered on the same object. The keyStr must not contain `[+]` or negative indices, whose positions are not fixed.
+//
+// Example:
+//
+//   unwatch, err := obj.Watch("db.pool", func(ev m2obj.ChangeEvent) {
+//     fmt.Println(ev.Op, ev.KeyStr, ev.Old, "->", ev.New) // set db.pool.size 10 -> 20
+//   })
+//   obj.Set("db.pool.size", 20)
+//   unwatch()
+func (o *Object) Watch(keyStr string, fn func(ev ChangeEvent)) (unwatch func(), err error) {
+	p, err := parseKeyStr(keyStr)
+	if err != nil {
+		return
+	}
+	for _, item := range p.keys {
+		if item.isPush || item.isIndex && item.index < 0 {
+			return nil, invalidKeyStrErr("the watched key {" + item.key + "} has no fixed position")
+		}
+	}
+	w := &watcher{
+		keys: p.keys,
+		fn:   fn,
+	}
+	func() {
+		defer o.lock()()
+		o.watchers = append(o.watchers, w)
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			defer o.lock()()
+			for i, ow := range o.watchers {
+				if ow == w { // copy on removing, the slice may be being iterated
+					o.watchers = append(o.watchers[:i:i], o.watchers[i+1:]...)
+					break
+				}
+			}
+		})
+	}, nil
+}
+
+// pendingChange
+//
+// A change being made in the object tree, see beginChange. A nil *pendingChange means nobody watches the change.
+type pendingChange struct {
+	tree      *tree
+	keyStr    string
+	op        ChangeOp
+	old       interface{}
+	listeners []func(ev ChangeEvent)
+}
+
+// pendingEvent
+//
+// A ChangeEvent waiting for the tree to be unlocked, see write.
+type pendingEvent struct {
+	ev        ChangeEvent
+	listeners []func(ev ChangeEvent)
+}
+
+// beginChange
+//
+// Prepares to report a change of the object located by the keys under o, the old is the object before the change (nil if it doesn't exist).
+// Call end on the returned change with the object after the change when the change is done.
+//
+// The histories of o and its ancestors are captured before the change as well, see EnableHistory.
+//
+// It costs nothing more than a walk to the root when there are no listeners of the change. Must be called with the tree locked for writing.
+func (o *Object) beginChange(keys []keyItem, op ChangeOp, old *Object) *pendingChange {
+	watched := false
+	for node := o; node != nil; node = node.parent {
+		node.captureHistory()
+		watched = watched || len(node.watchers) > 0
+	}
+	if !watched {
+		return nil
+	}
+	var listeners []func(ev ChangeEvent)
+	for node := o; node != nil; node = node.parent {
+		for _, w := range node.watchers {
+			if w.match(keys) {
+				listeners = append(listeners, w.fn)
+			}
+		}
+		if node.parent != nil {
+			keys = append([]keyItem{node.keyInParent()}, keys...)
+		}
+	}
+	if len(listeners) == 0 {
+		return nil
+	}
+	return &pendingChange{
+		tree:      o.getTree(),
+		keyStr:    joinKeyItems(keys),
+		op:        op,
+		old:       old.snapshot(),
+		listeners: listeners,
+	}
+}
+
+// end
+//
+// Queues the ChangeEvent of the change, the listeners of it are called after the tree is unlocked.
+func (c *pendingChange) end(obj *Object) {
+	if c == nil {
+		return
+	}
+	c.tree.events = append(c.tree.events, pendingEvent{
+		ev: ChangeEvent{
+			KeyStr: c.keyStr,
+			Op:     c.op,
+			Old:    c.old,
+			New:    obj.snapshot(),
+			source: c.tree.source,
+		},
+		listeners: c.listeners,
+	})
+}
+
+// dispatch
+//
+// Calls the listeners of the events in order.
+func dispatch(events []pendingEvent) {
+	for _, e := range events {
+		for _, fn := range e.listeners {
+			fn(e.ev)
+		}
+	}
+}
+
+// keyInParent
+//
+// Returns the key of the object in its parent.
+func (o *Object) keyInParent() keyItem {
+	switch o.parent.valOrNil().(type) {
+	case *groupData:
+		for k, v := range *o.parent.val.(*groupData) {
+			if v == o {
+				return keyItem{key: k}
+			}
+		}
+	case *arrayData:
+		for i, v := range *o.parent.val.(*arrayData) {
+			if v == o {
+				return indexKeyItem(i)
+			}
+		}
+	}
+	return keyItem{}
+}
+
+// indexKeyItem
+//
+// Returns the keyItem of an index in an Array.
+func indexKeyItem(index int) keyItem {
+	return keyItem{
+		key:     "[" + strconv.Itoa(index) + "]",
+		isIndex: true,
+		index:   index,
+	}
+}
+
+// joinKeyItems
+//
+// Joins the keys into a keyStr, the keys of Groups are escaped.
+func joinKeyItems(keys []keyItem) string {
+	pieces := make([]string, len(keys))
+	for i, item := range keys {
+		if item.isArrayKey() {
+			pieces[i] = item.key
+		} else {
+			pieces[i] = escapeKey(item.key)
+		}
+	}
+	return strings.Join(pieces, ".")
+}
+
+// snapshot
+//
+// Staticize without locking, returns nil for a nil object.
+func (o *Object) snapshot() interface{} {
+	if o == nil {
+		return nil
+	}
+	return o.staticize()
+}
@@ -0,0 +1,130 @@
+package m2obj
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObject_Watch(t *testing.T) {
+	obj := New(Group{
+		"db": Group{
+			"pool": Group{
+				"size": 10,
+			},
+			"name": "main",
+		},
+		"servers": Array{"s0", "s1"},
+	})
+	var rootEvents, poolEvents, serverEvents []ChangeEvent
+	unwatchRoot, err := obj.Watch("", func(ev ChangeEvent) {
+		rootEvents = append(rootEvents, ev)
+	})
+	assert.NoError(t, err)
+	unwatchPool, err := obj.Watch("db.pool", func(ev ChangeEvent) {
+		poolEvents = append(poolEvents, ev)
+	})
+	assert.NoError(t, err)
+	// watched on a child, the KeyStr is still full
+	_, err = obj.MustGet("servers").Watch("[1]", func(ev ChangeEvent) {
+		serverEvents = append(serverEvents, ev)
+	})
+	assert.NoError(t, err)
+
+	// set
+	assert.NoError(t, obj.Set("db.pool.size", 20))
+	assert.NoError(t, obj.Set("db.name", "backup"))
+	assert.NoError(t, obj.Set("db.pool.timeout.read", "1s"))
+	assert.Equal(t, []ChangeEvent{
+		{KeyStr: "db.pool.size", Op: OpSet, Old: 10, New: 20},
+		{KeyStr: "db.pool.timeout.read", Op: OpSet, Old: nil, New: "1s"},
+	}, poolEvents)
+	assert.Equal(t, 3, len(rootEvents))
+	assert.Equal(t, ChangeEvent{KeyStr: "db.name", Op: OpSet, Old: "main", New: "backup"}, rootEvents[1])
+
+	// above the watched path
+	poolEvents = nil
+	obj.MustGet("db").SetVal(Group{"pool": 1})
+	assert.NoError(t, obj.GroupMerge(New(Group{"db": Group{"pool": 2}}), true))
+	assert.Equal(t, []ChangeEvent{
+		{KeyStr: "db", Op: OpSet, Old: map[string]interface{}{
+			"pool": map[string]interface{}{
+				"size":    20,
+				"timeout": map[string]interface{}{"read": "1s"},
+			},
+			"name": "backup",
+		}, New: map[string]interface{}{"pool": 1}},
+		{KeyStr: "", Op: OpSet, Old: map[string]interface{}{
+			"db":      map[string]interface{}{"pool": 1},
+			"servers": []interface{}{"s0", "s1"},
+		}, New: map[string]interface{}{
+			"db":      map[string]interface{}{"pool": 2},
+			"servers": []interface{}{"s0", "s1"},
+		}},
+	}, poolEvents)
+
+	// arrays
+	rootEvents = nil
+	servers := obj.MustGet("servers")
+	servers.ArrPush("s2")
+	servers.ArrInsert(1, "s0.5")
+	servers.ArrSet(0, "s")
+	servers.ArrPop()
+	servers.ArrShift()
+	assert.NoError(t, obj.Set("servers.[+]", "s3"))
+	assert.NoError(t, obj.Unset("servers.[-1]"))
+	servers.ArrPushAll("a", "b")
+	assert.Equal(t, []ChangeEvent{
+		{KeyStr: "servers.[2]", Op: OpInsert, New: "s2"},
+		{KeyStr: "servers.[1]", Op: OpInsert, New: "s0.5"},
+		{KeyStr: "servers.[0]", Op: OpSet, Old: "s0", New: "s"},
+		{KeyStr: "servers.[3]", Op: OpRemove, Old: "s2"},
+		{KeyStr: "servers.[0]", Op: OpRemove, Old: "s"},
+		{KeyStr: "servers.[2]", Op: OpInsert, New: "s3"},
+		{KeyStr: "servers.[2]", Op: OpRemove, Old: "s3"},
+		{KeyStr: "servers.[2]", Op: OpInsert, New: "a"},
+		{KeyStr: "servers.[3]", Op: OpInsert, New: "b"},
+	}, rootEvents)
+	assert.Equal(t, []ChangeEvent{
+		{KeyStr: "servers.[1]", Op: OpInsert, New: "s0.5"},
+	}, serverEvents)
+
+	// remove and unwatch
+	rootEvents, poolEvents = nil, nil
+	unwatchPool()
+	unwatchPool()
+	assert.True(t, obj.Remove("db.pool"))
+	assert.Nil(t, poolEvents)
+	assert.Equal(t, []ChangeEvent{
+		{KeyStr: "db.pool", Op: OpRemove, Old: 2},
+	}, rootEvents)
+	unwatchRoot()
+	assert.NoError(t, obj.Set("x", 1))
+	assert.Equal(t, 1, len(rootEvents))
+
+	// escaped keys
+	var escaped []ChangeEvent
+	_, err = obj.Watch(`hosts.example\.com`, func(ev ChangeEvent) {
+		escaped = append(escaped, ev)
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, obj.Set(`hosts.example\.com.port`, 80))
+	assert.NoError(t, obj.Set(`hosts.example.port`, 80))
+	assert.Equal(t, []ChangeEvent{
+		{KeyStr: `hosts.example\.com.port`, Op: OpSet, New: 80},
+	}, escaped)
+
+	// invalid keyStr
+	for _, keyStr := range []string{`"`, "servers.[+]", "servers.[-1]"} {
+		_, err = obj.Watch(keyStr, func(ev ChangeEvent) {})
+		assert.True(t, errors.Is(err, ErrInvalidKeyStr), keyStr)
+	}
+}
+
+func TestChangeOp_String(t *testing.T) {
+	assert.Equal(t, "set", OpSet.String())
+	assert.Equal(t, "remove", OpRemove.String())
+	assert.Equal(t, "insert", OpInsert.String())
+	assert.Equal(t, "unknown", ChangeOp(0).String())
+}